@@ -0,0 +1,329 @@
+package main
+
+import (
+	"baryon/marshaler"
+	"baryon/tool"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultLayout is the layout of the outputs of a batch.
+const defaultLayout = "{id}{ext}"
+
+// expandInputs returns the files of args: the files matching the arguments
+// with glob metacharacters, the R files found under the directories, and
+// the other arguments as is, without duplicates. batch reports whether args
+// designate several files or may do so.
+func expandInputs(args []string) (paths []string, batch bool, err error) {
+	paths = []string{}
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(matches) == 0 {
+				return nil, false, fmt.Errorf("%s: no file matches", arg)
+			}
+			for _, match := range matches {
+				add(match)
+			}
+			batch = true
+			continue
+		}
+		stat, err := os.Stat(arg)
+		if err != nil {
+			return nil, false, err
+		}
+		if !stat.IsDir() {
+			add(arg)
+			continue
+		}
+		found, err := rFiles(arg)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(found) == 0 {
+			return nil, false, fmt.Errorf("%s: no R file found", arg)
+		}
+		for _, path := range found {
+			add(path)
+		}
+		batch = true
+	}
+	return paths, batch || len(paths) > 1, nil
+}
+
+// rFiles returns the R files under the directory dir, sorted, skipping the
+// hidden directories.
+func rFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext == ".R" || ext == ".r" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// batchResult is the result of the generation of a file of a batch.
+type batchResult struct {
+	// path of the file.
+	path string
+	// id of the tool.
+	id string
+	// tool is the parsed tool.
+	tool *tool.Tool
+	// output is the output of a marshaler.Marshaler.
+	output []byte
+	// files are the output of a marshaler.FilesMarshaler.
+	files []marshaler.File
+	// err is the error failing the generation.
+	err error
+}
+
+// outputPath returns the path of the output of the result: the layout of
+// options, under their --output-dir or the directory of the file, where
+// {id} is the id of the tool, or {name} without id, {name} the name of the
+// file without extension,
+// {dir} its directory, {format} the format and {ext} the extension of the
+// format, empty for the files of the package formats, written into the
+// directory at the returned path.
+func (r batchResult) outputPath(options *generateOptions) string {
+	base := options.outputDir
+	if base == "" {
+		base = filepath.Dir(r.path)
+	}
+	extension := options.extension()
+	if r.files != nil {
+		extension = ""
+	}
+	path := strings.NewReplacer(
+		"{id}", outputId(r.id, r.path),
+		"{name}", fileName(r.path),
+		"{dir}", filepath.Dir(r.path),
+		"{format}", options.format,
+		"{ext}", extension,
+	).Replace(options.layout)
+	return filepath.Join(base, path)
+}
+
+// fileName returns the name of the file at path without extension.
+func fileName(path string) string {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// outputId returns the id naming the output of the tool of the file at
+// path: id, or the name of the file when the tool has no id. It is empty
+// for a tool of the standard input without id.
+func outputId(id string, path string) string {
+	if id != "" || path == "" {
+		return id
+	}
+	return fileName(path)
+}
+
+// generateBatch generates the tools of paths with at most options.jobs
+// concurrent workers, and writes them as laid out by outputPath. It prints
+// the output or the error of each file in the order of paths, then a
+// summary, and returns 1 when any file failed.
+func generateBatch(options *generateOptions, paths []string, stdout, stderr io.Writer) int {
+	results := make([]batchResult, len(paths))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range max(1, min(options.jobs, len(paths))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = generateFile(options, paths[i], stderr)
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if docs, ok := options.docsMarshaler(); ok {
+		return writeSites(docs, options, results, stdout, stderr)
+	}
+
+	// Writes sequentially, so that a path claimed twice is reported on the
+	// second file whatever the scheduling.
+	claimed := map[string]string{}
+	failed := 0
+	for _, result := range results {
+		if result.err == nil {
+			output := result.outputPath(options)
+			if previous, ok := claimed[output]; ok {
+				result.err = fmt.Errorf("%s is also the output of %s", output, previous)
+			} else {
+				claimed[output] = result.path
+				result.err = result.write(output)
+			}
+			if result.err == nil {
+				fmt.Fprintf(stdout, "%s: %s\n", result.path, output)
+				continue
+			}
+		}
+		fmt.Fprintf(stderr, "%s: %v\n", result.path, result.err)
+		failed++
+	}
+	fmt.Fprintf(stdout, "%d generated, %d failed\n", len(paths)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// docsMarshaler returns the marshaler of the docs formats, reporting
+// whether the options generate one.
+func (o *generateOptions) docsMarshaler() (marshaler.DocsMarshaler, bool) {
+	if o.template != "" || (o.format != "docs" && o.format != "docs-html") {
+		return marshaler.DocsMarshaler{}, false
+	}
+	return marshaler.DocsMarshaler{HTML: o.format == "docs-html"}, true
+}
+
+// site reports whether the options generate a docs format, whose index
+// lists all the files.
+func (o *generateOptions) site() bool {
+	if o.targets != nil {
+		_, docs := o.targets.Outputs["docs"]
+		_, html := o.targets.Outputs["docs-html"]
+		return docs || html
+	}
+	_, ok := o.docsMarshaler()
+	return ok
+}
+
+// writeSites writes the pages of the results of a docs format into the
+// directories of their outputs as laid out by outputPath, each directory
+// with an index of its pages when it has several, named after the tool ids.
+// It prints the page or the error of each file in the order of results,
+// then a summary, and returns 1 when any file failed.
+func writeSites(docs marshaler.DocsMarshaler, options *generateOptions, results []batchResult, stdout, stderr io.Writer) int {
+	dirs := []string{}
+	tools := map[string][]*tool.Tool{}
+	pages := make([]string, len(results))
+	claimed := map[string]string{}
+	for i, result := range results {
+		if result.err != nil {
+			continue
+		}
+		dir := filepath.Dir(result.outputPath(options))
+		pages[i] = filepath.Join(dir, docs.PagePath(result.tool))
+		if previous, ok := claimed[pages[i]]; ok {
+			results[i].err = fmt.Errorf("%s is also the output of %s", pages[i], previous)
+			continue
+		}
+		claimed[pages[i]] = result.path
+		if _, ok := tools[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		tools[dir] = append(tools[dir], result.tool)
+	}
+	errs := map[string]error{}
+	for _, dir := range dirs {
+		files, err := docs.MarshalSite(tools[dir])
+		if err == nil {
+			err = writeFiles(dir, files)
+		}
+		errs[dir] = err
+	}
+	failed := 0
+	for i, result := range results {
+		if result.err == nil {
+			result.err = errs[filepath.Dir(pages[i])]
+		}
+		if result.err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", result.path, result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", result.path, pages[i])
+	}
+	fmt.Fprintf(stdout, "%d generated, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// generateFile returns the batchResult of the file at path.
+func generateFile(options *generateOptions, path string, stderr io.Writer) batchResult {
+	result := batchResult{path: path}
+	t, selected, err := options.prepare(path, stderr)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.id, result.tool = t.Id, t
+	if files, ok := selected.(marshaler.FilesMarshaler); ok {
+		result.files, result.err = files.MarshalFiles(t)
+		return result
+	}
+	result.output, result.err = selected.Marshal(t)
+	return result
+}
+
+// write writes the result to the path returned by outputPath.
+func (r batchResult) write(path string) error {
+	if r.files != nil {
+		return writeFiles(path, r.files)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, r.output, 0644)
+}
+
+// generateTargets generates the tools of paths in each format of the
+// outputs of options.targets, laid out under the directory of the
+// configuration, and returns 1 when any file failed.
+func generateTargets(options *generateOptions, paths []string, stdout, stderr io.Writer) int {
+	names := []string{}
+	for format := range options.targets.Outputs {
+		names = append(names, format)
+	}
+	slices.Sort(names)
+	status := 0
+	for _, format := range names {
+		target := *options
+		target.targets = nil
+		target.format = format
+		target.outputDir = options.targets.dir
+		target.layout = options.targets.Outputs[format]
+		if target.layout == "" {
+			target.layout = "{format}/" + defaultLayout
+		}
+		if generateBatch(&target, paths, stdout, stderr) != 0 {
+			status = 1
+		}
+	}
+	return status
+}
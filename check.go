@@ -0,0 +1,224 @@
+package main
+
+import (
+	"baryon/lint"
+	"baryon/marshaler"
+	"baryon/parser"
+	"baryon/tool"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// lint returns the diagnostics of the source, with the rules configured
+// for it.
+func (s *sourceFile) lint() []lint.Diagnostic {
+	return lint.Lint(&lint.Source{Tool: s.tool, Entries: parser.Entries(s.in), In: s.in}, s.config.Lint)
+}
+
+// check returns an error describing the first diagnostic of the source
+// failing a check, strict or as strict as its configuration.
+func (s *sourceFile) check(strict bool) error {
+	failing := lint.Failing(s.lint(), strict || s.config.Strict)
+	if len(failing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s [%s]", failing[0].Message, failing[0].Rule)
+}
+
+// formatDiagnostic returns d as printed by the lint command: its position,
+// severity, message and rule, followed by the suggested fix.
+func (s *sourceFile) formatDiagnostic(d lint.Diagnostic) string {
+	position := displayPath(s.path)
+	if line := (&lint.Source{In: s.in}).Line(d); line > 0 {
+		position += fmt.Sprintf(":%d", line)
+	}
+	formatted := fmt.Sprintf("%s: %s: %s [%s]\n", position, d.Severity, d.Message, d.Rule)
+	if d.Fix != "" {
+		formatted += fmt.Sprintf("\tfix: %s\n", d.Fix)
+	}
+	return formatted
+}
+
+// validateTool returns an error when t cannot be generated.
+func validateTool(t *tool.Tool) error {
+	if t.Id == "" {
+		return fmt.Errorf("the tool has no id")
+	}
+	if t.Command != nil && t.Requirements != nil {
+		if _, err := marshaler.NewTemplateData(t); err != nil {
+			return err
+		}
+	}
+	_, err := marshaler.GalaxyMarshaler{}.Marshal(t)
+	return err
+}
+
+// runValidate implements the validate command.
+func runValidate(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	parserName := parserFlag(flags)
+	strict := flags.Bool("strict", false, "fail on the warnings of the lint command")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	status := 0
+	for _, path := range paths {
+		err := func() error {
+			source, err := readSource(path, *parserName)
+			if err != nil {
+				return err
+			}
+			if err := validateTool(source.tool); err != nil {
+				return err
+			}
+			return source.check(*strict)
+		}()
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", displayPath(path), err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", displayPath(path))
+	}
+	return status
+}
+
+// runLint implements the lint command.
+func runLint(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	parserName := parserFlag(flags)
+	strict := flags.Bool("strict", false, "exit with status 1 on warnings too, not only on errors")
+	list := flags.Bool("rules", false, "list the rules, with their id and severity")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if *list {
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		for _, rule := range lint.Rules {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Id, rule.Severity, rule.Description)
+		}
+		w.Flush()
+		return 0
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	status := 0
+	for _, path := range paths {
+		source, err := readSource(path, *parserName)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: error: %v [parse]\n", displayPath(path), err)
+			status = 1
+			continue
+		}
+		diagnostics := source.lint()
+		for _, diagnostic := range diagnostics {
+			fmt.Fprint(stdout, source.formatDiagnostic(diagnostic))
+		}
+		if len(lint.Failing(diagnostics, *strict || source.config.Strict)) > 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+// runInspect implements the inspect command.
+func runInspect(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	parserName := parserFlag(flags)
+	format := flags.String("format", "text", "output format: text, json or yaml")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(stderr, "baryon: %s takes a single file\n", name)
+		return 2
+	}
+	t, err := parseFile(flags.Arg(0), *parserName)
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	var output []byte
+	switch *format {
+	case "text":
+		output = []byte(inspectTool(t))
+	case "json":
+		output, err = marshaler.JSONMarshaler{}.Marshal(t)
+	case "yaml":
+		output, err = marshaler.YAMLMarshaler{}.Marshal(t)
+	default:
+		fmt.Fprintf(stderr, "baryon: unknown format %q; available formats: text, json, yaml\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	stdout.Write(output)
+	return 0
+}
+
+// inspectTool returns the summary of t printed by the inspect command.
+func inspectTool(t *tool.Tool) string {
+	builder := &strings.Builder{}
+	w := tabwriter.NewWriter(builder, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "id:\t%s\n", t.Id)
+	fmt.Fprintf(w, "name:\t%s\n", t.Name)
+	fmt.Fprintf(w, "description:\t%s\n", strings.ReplaceAll(strings.TrimSpace(t.Description), "\n", " "))
+	if t.Requirements != nil {
+		for _, container := range t.Requirements.Container {
+			fmt.Fprintf(w, "container:\t%s (%s)\n", container.Value, container.Type)
+			for _, volume := range container.Volumes {
+				fmt.Fprintf(w, "volume:\t%s:%s\n", volume.HostPath, volume.GuestPath)
+			}
+		}
+	}
+	if t.Command != nil {
+		fmt.Fprintf(w, "command:\t%s\n", t.Command.Value)
+	}
+	if t.Category != "" {
+		fmt.Fprintf(w, "category:\t%s\n", t.Category)
+	}
+	if t.Resources != nil {
+		fmt.Fprintf(w, "resources:\tcpus=%d memory=%dM time=%dm\n",
+			t.Resources.Cpus, t.Resources.Memory, t.Resources.Time)
+	}
+	w.Flush()
+	if t.Inputs != nil && len(t.Inputs.Param) > 0 {
+		fmt.Fprintln(builder, "\ninputs:")
+		w = tabwriter.NewWriter(builder, 0, 4, 2, ' ', 0)
+		for _, param := range t.Inputs.Param {
+			optional := ""
+			if param.Optional {
+				optional = "optional"
+			}
+			options := []string{}
+			for _, option := range param.Options {
+				options = append(options, option.Value)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", param.Name, param.Type, param.Value,
+				strings.Join(options, ","), optional)
+		}
+		w.Flush()
+	}
+	if t.Outputs != nil && len(t.Outputs.Data) > 0 {
+		fmt.Fprintln(builder, "\noutputs:")
+		w = tabwriter.NewWriter(builder, 0, 4, 2, ' ', 0)
+		for _, data := range t.Outputs.Data {
+			fmt.Fprintf(w, "  %s\t%s\n", data.Name, data.Format)
+		}
+		w.Flush()
+	}
+	if t.Tests != nil && len(t.Tests.Test) > 0 {
+		fmt.Fprintf(builder, "\ntests: %d\n", len(t.Tests.Test))
+	}
+	return builder.String()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"baryon/lint"
+	"baryon/parser"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the configuration file of a project.
+const configFile = "baryon.yaml"
+
+// config is the configuration of a project, read from its configFile.
+type config struct {
+	// dir is the directory of the configFile, the base of the Outputs.
+	dir string
+	// Container is the image of the tools without container instruction.
+	Container string `yaml:"container"`
+	// ContainerType is the type of Container, docker by default.
+	ContainerType string `yaml:"container_type"`
+	// Organization is the organization of the creator of the tools.
+	Organization string `yaml:"organization"`
+	// IdPrefix prefixes the ids of the tools.
+	IdPrefix string `yaml:"id_prefix"`
+	// Owner is the Tool Shed username owning the repositories of the
+	// package format and of the suites, as --owner.
+	Owner string `yaml:"owner"`
+	// Strict fails the generation and the validation on the warnings of
+	// lint, as --strict.
+	Strict bool `yaml:"strict"`
+	// Outputs are the layouts of the outputs of generate, by format,
+	// relative to dir.
+	Outputs map[string]string `yaml:"outputs"`
+	// Lint configures the rules of lint.
+	Lint lint.Config `yaml:"lint"`
+}
+
+// defaults returns the parser.Defaults of the configuration.
+func (c *config) defaults() parser.Defaults {
+	return parser.Defaults{
+		Container:     c.Container,
+		ContainerType: c.ContainerType,
+		Organization:  c.Organization,
+		IdPrefix:      c.IdPrefix,
+	}
+}
+
+// readConfig reads the configFile at path, rejecting the unknown fields.
+func readConfig(path string) (*config, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &config{dir: filepath.Dir(path)}
+	decoder := yaml.NewDecoder(bytes.NewReader(in))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.Lint.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// cachedConfig is a configuration read, with the stamp of its file when it
+// was read.
+type cachedConfig struct {
+	stamp  fileStamp
+	config *config
+}
+
+var (
+	// configsMutex guards configs, read by the workers of a batch.
+	configsMutex sync.Mutex
+	// configs are the configurations read, by path. A configuration is
+	// read again when its file changes, as while generate --watch runs.
+	configs = map[string]cachedConfig{}
+)
+
+// configPaths returns the paths where the configFile of the file at path,
+// or of the standard input when path is empty, is looked for, in order:
+// the working directory and the root of the R package of the file.
+func configPaths(path string) []string {
+	paths := []string{configFile}
+	if path != "" {
+		if root := rPackageRoot(path); root != "" {
+			paths = append(paths, filepath.Join(root, configFile))
+		}
+	}
+	return paths
+}
+
+// findConfig returns the configuration of the file at path, or of the
+// standard input when path is empty: the first configFile of configPaths.
+// It returns an empty configuration when there is none.
+func findConfig(path string) (*config, error) {
+	configsMutex.Lock()
+	defer configsMutex.Unlock()
+	for _, configPath := range configPaths(path) {
+		stat, err := os.Stat(configPath)
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		stamp := fileStamp{modTime: stat.ModTime(), size: stat.Size()}
+		if cached, ok := configs[configPath]; ok && cached.stamp == stamp {
+			return cached.config, nil
+		}
+		c, err := readConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		configs[configPath] = cachedConfig{stamp: stamp, config: c}
+		return c, nil
+	}
+	return &config{dir: "."}, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"baryon/marshaler"
+	"baryon/parser"
+	"baryon/tool"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// runDiff implements the diff command.
+func runDiff(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	parserName := parserFlag(flags)
+	version := flags.String("version", "", "version of the old tool, read by default from the version of its tool XML or the DESCRIPTION of its R package")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+	oldSource, err := readSource(oldPath, *parserName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", oldPath, err)
+		return 1
+	}
+	newSource, err := readSource(newPath, *parserName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", newPath, err)
+		return 1
+	}
+	oldTool, err := oldSource.galaxyTool(*parserName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", oldPath, err)
+		return 1
+	}
+	newTool, err := newSource.galaxyTool(*parserName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", newPath, err)
+		return 1
+	}
+
+	changes := tool.Diff(oldTool, newTool)
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	breaking := 0
+	for _, change := range changes {
+		kind := "non-breaking"
+		if change.Breaking() {
+			kind = "breaking"
+			breaking++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t(%s)\n", kind, change.Subject, change.Message, change.Bump)
+	}
+	w.Flush()
+	if len(changes) == 0 {
+		fmt.Fprintln(stdout, "no change")
+		return 0
+	}
+	fmt.Fprintf(stdout, "%d changes, %d breaking: %s version", len(changes), breaking, tool.BumpOf(changes))
+	if *version == "" {
+		*version = oldSource.version()
+	}
+	if *version != "" {
+		next, err := tool.NextVersion(*version, changes)
+		if err != nil {
+			fmt.Fprintln(stdout)
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, " %s, from %s", next, *version)
+	}
+	fmt.Fprintln(stdout)
+	if breaking > 0 {
+		return 1
+	}
+	return 0
+}
+
+// isGalaxy reports whether the source is parsed as a Galaxy tool XML by the
+// parser named parserName.
+func (s *sourceFile) isGalaxy(parserName string) bool {
+	return parserName == "galaxy" ||
+		(parserName == "" || parserName == "auto") && bytes.HasPrefix(bytes.TrimSpace(s.in), []byte("<"))
+}
+
+// galaxyTool returns the tool of the source as read back from its Galaxy
+// tool XML, so that the tools of R files and of tool XMLs compare.
+func (s *sourceFile) galaxyTool(parserName string) (*tool.Tool, error) {
+	if s.isGalaxy(parserName) {
+		return s.tool, nil
+	}
+	out, err := marshaler.GalaxyMarshaler{}.Marshal(s.tool)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewGalaxy("").Parse(out)
+}
+
+// version returns the version of the source: the version attribute of its
+// tool XML, or the version of the R package containing it, empty when it
+// has none.
+func (s *sourceFile) version() string {
+	root := struct {
+		Version string `xml:"version,attr"`
+	}{}
+	if xml.Unmarshal(s.in, &root) == nil && root.Version != "" {
+		return root.Version
+	}
+	if s.path == "" {
+		return ""
+	}
+	return rDescription(s.path)["Version"]
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"baryon/parser"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runFmt implements the fmt command.
+func runFmt(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	check := flags.Bool("check", false, "list the files that are not formatted and exit with status 1, without rewriting them")
+	required := flags.String("required", parser.DefaultFormatStyle.Required, "spelling of the required instruction: ! or required")
+	width := flags.Int("width", parser.DefaultFormatStyle.Width, "length of the lines over which a namespace is written one instruction per line")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	style := parser.FormatStyle{Required: *required, Width: *width}
+	if style.Required != "!" && style.Required != "required" {
+		fmt.Fprintf(stderr, "baryon: --required must be ! or required, not %q\n", style.Required)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		in, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		out, err := parser.Format(in, style)
+		if err != nil {
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		if *check {
+			if !bytes.Equal(in, out) {
+				fmt.Fprintln(stdout, displayPath(""))
+				return 1
+			}
+			return 0
+		}
+		stdout.Write(out)
+		return 0
+	}
+	paths, _, err := expandInputs(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	status := 0
+	for _, path := range paths {
+		changed, err := formatFile(path, style, !*check)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		if changed {
+			fmt.Fprintln(stdout, path)
+			if *check {
+				status = 1
+			}
+		}
+	}
+	return status
+}
+
+// formatFile formats the R file at path in style, rewriting it when write
+// is true. It reports whether the file is not formatted.
+func formatFile(path string, style parser.FormatStyle, write bool) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	out, err := parser.Format(in, style)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(in, out) {
+		return false, nil
+	}
+	if write {
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"baryon/marshaler"
+	"baryon/tool"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// format is an output format of the generate command.
+type format struct {
+	// description is the help of the format.
+	description string
+	// extension is the extension of the file written for a tool into
+	// --output-dir, when the marshaler is not a marshaler.FilesMarshaler.
+	extension string
+	// marshaler returns the marshaler of the tool of the file at path.
+	marshaler func(o *generateOptions, path string) (marshaler.Marshaler, error)
+}
+
+// formats are the built-in formats of the generate command, by name.
+var formats = map[string]format{
+	"galaxy": {"Galaxy tool XML", ".xml",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.GalaxyMarshaler{}, nil
+		}},
+	"bash": {"Bash script running the container", ".sh",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.BashMarshaler{}, nil
+		}},
+	"python": {"Python script running the container", ".py",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.PythonMarshaler{}, nil
+		}},
+	"python-package": {"installable Python package, as a tar archive", ".tar",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.PythonPackageMarshaler{}, nil
+		}},
+	"json": {"JSON representation of the tool", ".json",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.JSONMarshaler{}, nil
+		}},
+	"yaml": {"YAML representation of the tool", ".yaml",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.YAMLMarshaler{}, nil
+		}},
+	"docs": {"Markdown documentation", ".md",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.DocsMarshaler{}, nil
+		}},
+	"docs-html": {"HTML documentation", ".html",
+		func(*generateOptions, string) (marshaler.Marshaler, error) {
+			return marshaler.DocsMarshaler{HTML: true}, nil
+		}},
+	"package": {"Tool Shed repository, as a tar archive", ".tar",
+		func(o *generateOptions, path string) (marshaler.Marshaler, error) {
+			testData := o.testData
+			if testData == "" && path != "" {
+				testData = filepath.Dir(path)
+			}
+			owner, err := o.shedOwner(path)
+			if err != nil {
+				return nil, err
+			}
+			return marshaler.ShedMarshaler{TestData: testData, Categories: o.categories, Owner: owner}, nil
+		}},
+	"dockerfile": {"Dockerfile of the image of the tool, with its build context in --output-dir", "",
+		func(_ *generateOptions, path string) (marshaler.Marshaler, error) {
+			return dockerfileMarshaler(path), nil
+		}},
+	"slurm": {"Slurm batch script", ".sbatch",
+		func(o *generateOptions, _ string) (marshaler.Marshaler, error) {
+			return marshaler.SlurmMarshaler{OutputDir: o.logDir}, nil
+		}},
+	"kubernetes": {"Kubernetes Job", ".yaml",
+		func(o *generateOptions, _ string) (marshaler.Marshaler, error) {
+			kubernetes := marshaler.KubernetesMarshaler{Outdir: o.outputsVolume}
+			if o.values != "" {
+				in, err := os.ReadFile(o.values)
+				if err != nil {
+					return nil, err
+				}
+				if kubernetes.Values, err = marshaler.ReadValues(in); err != nil {
+					return nil, err
+				}
+			}
+			return kubernetes, nil
+		}},
+}
+
+// formatNames returns the names of the built-in formats, sorted.
+func formatNames() []string {
+	names := []string{}
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// generateOptions are the flags of the generate command.
+type generateOptions struct {
+	format        string
+	output        string
+	outputDir     string
+	parser        string
+	strict        bool
+	template      string
+	testData      string
+	categories    []string
+	owner         string
+	values        string
+	logDir        string
+	outputsVolume string
+	suite         string
+	jobs          int
+	layout        string
+	watch         bool
+	pollInterval  time.Duration
+	// targets is the configuration whose outputs are generated, instead
+	// of format.
+	targets *config
+	// legacy writes only the files of the package formats into outputDir.
+	legacy bool
+}
+
+// register adds the flags of the options to flags.
+func (o *generateOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.format, "format", "galaxy",
+		"output format: "+strings.Join(formatNames(), ", ")+",\n"+
+			"or the mode of a "+marshaler.PluginPrefix+"<mode> plugin")
+	flags.StringVar(&o.output, "output", "", "write the output into this file, instead of the standard output")
+	flags.StringVar(&o.outputDir, "output-dir", "",
+		"write the files of the package formats, or the <id><extension> file of the\n"+
+			"others, into this directory")
+	parserFlagVar(flags, &o.parser)
+	flags.BoolVar(&o.strict, "strict", false, "fail on the warnings of the lint command")
+	flags.StringVar(&o.template, "template", "",
+		"render the tool through the text/template file at this path, instead of --format")
+	flags.StringVar(&o.testData, "test-data", "",
+		"directory of the test data of the package format, the directory of the file by default")
+	flags.Func("category", "category of the Tool Shed repository of the package format, repeatable",
+		func(category string) error {
+			o.categories = append(o.categories, category)
+			return nil
+		})
+	flags.StringVar(&o.owner, "owner", "",
+		"Tool Shed username owning the repositories of the package format and of --suite,\n"+
+			"the owner of baryon.yaml by default")
+	flags.StringVar(&o.values, "values", "",
+		"YAML or JSON file of the values of the parameters of the kubernetes format")
+	flags.StringVar(&o.logDir, "log-dir", "",
+		"directory of the logs and of the outputs of the slurm format")
+	flags.StringVar(&o.outputsVolume, "outputs-volume", "",
+		"volume of the outputs of the kubernetes format: pvc:<claim>[/<path>] or a host path")
+	flags.IntVar(&o.jobs, "jobs", runtime.NumCPU(), "number of files generated concurrently")
+	flags.StringVar(&o.layout, "layout", defaultLayout,
+		"path of the output of each file when there are several, under --output-dir or the\n"+
+			"directory of the file, where {id} is the id of the tool, or {name} without id,\n"+
+			"{name} the name of the file without extension, {dir} its directory, {format} the\n"+
+			"format and {ext} its extension, empty for the package formats written as directories")
+	flags.BoolVar(&o.watch, "watch", false, "regenerate the files when they change, until interrupted")
+	flags.DurationVar(&o.pollInterval, "poll-interval", defaultPollInterval,
+		"interval between two polls of the files by --watch")
+	flags.StringVar(&o.suite, "suite", "",
+		"write the suite of this name of all the files given, with its tool_conf.xml, into --output-dir")
+}
+
+// marshaler returns the marshaler of the options for the tool of the file at
+// path. It returns an error listing the available formats when the format is
+// neither built-in nor a plugin.
+func (o *generateOptions) marshaler(path string) (marshaler.Marshaler, error) {
+	if o.template != "" {
+		return marshaler.NewTemplateMarshalerFromFile(o.template)
+	}
+	if format, ok := formats[o.format]; ok {
+		return format.marshaler(o, path)
+	}
+	plugin, err := marshaler.LookupPlugin(o.format)
+	if errors.Is(err, exec.ErrNotFound) {
+		available := formatNames()
+		for _, mode := range marshaler.Plugins() {
+			if !slices.Contains(available, mode) {
+				available = append(available, mode)
+			}
+		}
+		return nil, fmt.Errorf("unknown format %q; available formats: %s",
+			o.format, strings.Join(available, ", "))
+	}
+	return plugin, err
+}
+
+// extension returns the extension of the file written into --output-dir.
+func (o *generateOptions) extension() string {
+	if o.template != "" {
+		return ""
+	}
+	if format, ok := formats[o.format]; ok {
+		return format.extension
+	}
+	return "." + o.format
+}
+
+// runGenerate implements the generate command.
+func runGenerate(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	options := &generateOptions{}
+	options.register(flags)
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if options.output != "" && options.outputDir != "" {
+		fmt.Fprintln(stderr, "baryon: --output and --output-dir are exclusive")
+		return 2
+	}
+	paths, batch, err := expandInputs(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	formatSet := false
+	flags.Visit(func(f *flag.Flag) { formatSet = formatSet || f.Name == "format" })
+	if !formatSet && len(paths) > 0 && options.output == "" && options.outputDir == "" &&
+		options.template == "" && options.suite == "" {
+		c, err := findConfig(paths[0])
+		if err != nil {
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		if len(c.Outputs) > 0 {
+			options.targets = c
+		}
+	}
+	if options.watch {
+		if flags.NArg() == 0 {
+			fmt.Fprintln(stderr, "baryon: --watch cannot watch the standard input")
+			return 2
+		}
+		if batch && options.output != "" {
+			fmt.Fprintln(stderr, "baryon: --output takes a single file, use --output-dir or --layout")
+			return 2
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return watch(ctx, options, flags.Args(), stdout, stderr)
+	}
+	if options.suite != "" {
+		if err := generateSuite(options, paths, stderr); err != nil {
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if options.targets != nil {
+		return generateTargets(options, paths, stdout, stderr)
+	}
+	if batch {
+		if options.output != "" {
+			fmt.Fprintln(stderr, "baryon: --output takes a single file, use --output-dir or --layout")
+			return 2
+		}
+		return generateBatch(options, paths, stdout, stderr)
+	}
+	var path string
+	if len(paths) > 0 {
+		path = paths[0]
+	}
+	if err := generate(options, path, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runLegacy implements the former invocation, baryon [flags] file [mode],
+// as a shorthand for the generate command. Its -output-dir is also the
+// directory of the slurm mode and the volume of the kubernetes mode, and
+// only the package modes write into it.
+func runLegacy(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("baryon", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { printUsage(stderr) }
+	options := &generateOptions{}
+	flags.StringVar(&options.template, "template", "", "")
+	flags.StringVar(&options.outputDir, "output-dir", "", "")
+	flags.StringVar(&options.testData, "test-data", "", "")
+	flags.Func("category", "", func(category string) error {
+		options.categories = append(options.categories, category)
+		return nil
+	})
+	flags.StringVar(&options.values, "values", "", "")
+	flags.StringVar(&options.suite, "suite", "", "")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if options.suite != "" {
+		if err := generateSuite(options, flags.Args(), stderr); err != nil {
+			fmt.Fprintf(stderr, "baryon: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	options.format = flags.Arg(1)
+	if options.format == "" {
+		options.format = "galaxy"
+	}
+	options.logDir, options.outputsVolume = options.outputDir, options.outputDir
+	options.legacy = true
+	if err := generate(options, flags.Arg(0), stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// generate writes the tool of the file at path, or of the standard input
+// when path is empty, as configured by options.
+func generate(options *generateOptions, path string, stdout, stderr io.Writer) error {
+	t, selected, err := options.prepare(path, stderr)
+	if err != nil {
+		return err
+	}
+	if options.outputDir != "" {
+		if files, ok := selected.(marshaler.FilesMarshaler); ok {
+			files, err := files.MarshalFiles(t)
+			if err != nil {
+				return err
+			}
+			return writeFiles(options.outputDir, files)
+		}
+	}
+	output, err := selected.Marshal(t)
+	if err != nil {
+		return err
+	}
+	switch {
+	case options.output != "":
+		return os.WriteFile(options.output, output, 0644)
+	case options.outputDir != "" && !options.legacy:
+		if err := os.MkdirAll(options.outputDir, 0755); err != nil {
+			return err
+		}
+		id := outputId(t.Id, path)
+		if id == "" {
+			return fmt.Errorf("the tool has no id to name its output in %s", options.outputDir)
+		}
+		return os.WriteFile(filepath.Join(options.outputDir, id+options.extension()), output, 0644)
+	}
+	_, err = stdout.Write(output)
+	return err
+}
+
+// prepare parses the tool of the file at path and returns it with its
+// marshaler, forwarding the diagnostics of the plugins to stderr.
+func (o *generateOptions) prepare(path string, stderr io.Writer) (*tool.Tool, marshaler.Marshaler, error) {
+	source, err := readSource(path, o.parser)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.strict || source.config.Strict {
+		if err := source.check(true); err != nil {
+			return nil, nil, err
+		}
+	}
+	t := source.tool
+	selected, err := o.marshaler(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plugin, ok := selected.(*marshaler.PluginMarshaler); ok {
+		plugin.Stderr = stderr
+	}
+	if shed, ok := selected.(marshaler.ShedMarshaler); ok && shed.Owner == "" {
+		warnNoOwner(path, stderr)
+	}
+	return t, selected, nil
+}
+
+// shedOwner returns the Tool Shed owner of the tool of the file at path:
+// --owner, or the owner of its configuration, empty when there is none.
+func (o *generateOptions) shedOwner(path string) (string, error) {
+	if o.owner != "" {
+		return o.owner, nil
+	}
+	c, err := findConfig(path)
+	if err != nil {
+		return "", err
+	}
+	return c.Owner, nil
+}
+
+// warnNoOwner warns that the Tool Shed repositories of the file at path
+// have no owner, as planemo then asks for one.
+func warnNoOwner(path string, stderr io.Writer) {
+	fmt.Fprintf(stderr, "%s: warning: the Tool Shed repository has no owner\n\tfix: set owner in %s, or pass --owner\n",
+		displayPath(path), configFile)
+}
+
+// displayPath returns path for the messages, the standard input when empty.
+func displayPath(path string) string {
+	if path == "" {
+		return "<stdin>"
+	}
+	return path
+}
+
+// generateSuite writes into --output-dir the suite of the tools of paths.
+// A tool without category is listed in the section of its R package, or of
+// the suite outside of a package.
+func generateSuite(options *generateOptions, paths []string, stderr io.Writer) error {
+	if options.outputDir == "" {
+		return fmt.Errorf("--suite requires --output-dir")
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("No file provided.")
+	}
+	tools := []*tool.Tool{}
+	testData := map[string]string{}
+	for _, path := range paths {
+		source, err := readSource(path, options.parser)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if options.strict || source.config.Strict {
+			if err := source.check(true); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+		t := source.tool
+		if t.Category == "" {
+			t.Category = rPackage(path)
+		}
+		tools = append(tools, t)
+		testData[t.Id] = filepath.Dir(path)
+	}
+	owner, err := options.shedOwner(paths[0])
+	if err != nil {
+		return err
+	}
+	if owner == "" {
+		warnNoOwner(paths[0], stderr)
+	}
+	files, err := marshaler.SuiteMarshaler{
+		Name:       options.suite,
+		Categories: options.categories,
+		Owner:      owner,
+		TestData:   testData,
+	}.MarshalSuite(tools)
+	if err != nil {
+		return err
+	}
+	return writeFiles(options.outputDir, files)
+}
+
+// dockerfileMarshaler returns the DockerfileMarshaler of the tool of the
+// file at path, installing the dependencies of its R package with
+// BiocManager for Bioconductor packages, and install.packages otherwise,
+// and tagging the image with the version of the package.
+func dockerfileMarshaler(path string) marshaler.DockerfileMarshaler {
+	dockerfile := marshaler.DockerfileMarshaler{}
+	if path == "" {
+		return dockerfile
+	}
+	dockerfile.Context = filepath.Dir(path)
+	dockerfile.Version = rDescription(path)["Version"]
+	if _, ok := rDescription(path)["biocViews"]; ok {
+		dockerfile.BiocPackages = rDependencies(path)
+	} else {
+		dockerfile.Packages = rDependencies(path)
+	}
+	return dockerfile
+}
@@ -1,3 +1,5 @@
 module baryon
 
 go 1.22.3
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"baryon/parser"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// rFunctionNameRegex matches the syntactic names of R functions.
+var rFunctionNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._]*$`)
+
+// defaultContainer is the container of the tools written by the init
+// command, when the configuration has none.
+const defaultContainer = "rocker/r-ver:4.4.1"
+
+// skeleton is the R function written by the init command.
+var skeleton = template.Must(template.New("skeleton").Parse(`#' {{.Name}}
+#'
+#' @description Describe what {{.Name}} does.
+#' $B{container({{.Container}});command(Rscript /scripts/{{.Name}}.R $input $threads);id({{.Id}});name({{.Name}})}
+#'
+#' @param input the file to analyze $B{type(data);!}
+#' @param threads the number of threads $B{type(integer);value(1)}
+#' @return $B{data(result.txt,txt)}
+#' @export
+{{.Name}} <- function(input, threads = 1) {
+        writeLines(readLines(input), "result.txt")
+}
+
+if (sys.nframe() == 0) {
+        args <- commandArgs(trailingOnly = TRUE)
+        {{.Name}}(args[1], as.integer(args[2]))
+}
+`))
+
+// runInit implements the init command.
+func runInit(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	output := flags.String("output", "", "write the skeleton or the scaffolded file into this file, instead of the standard output or in place")
+	diff := flags.Bool("diff", false, "print the changes of the scaffolded file as a unified diff, instead of writing it")
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if info, err := os.Stat(flags.Arg(0)); err == nil && !info.IsDir() {
+		return runScaffold(flags.Arg(0), *output, *diff, stdout, stderr)
+	}
+	function := flags.Arg(0)
+	if !rFunctionNameRegex.MatchString(function) {
+		fmt.Fprintf(stderr, "baryon: %q is neither a file nor a valid R function name\n", function)
+		return 2
+	}
+	builder := &strings.Builder{}
+	data := struct{ Name, Id, Container string }{function, strings.ReplaceAll(function, ".", "_"), defaultContainer}
+	if err := skeleton.Execute(builder, data); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	if *output == "" {
+		io.WriteString(stdout, builder.String())
+		return 0
+	}
+	return writeNew(*output, []byte(builder.String()), stderr)
+}
+
+// writeNew writes the file at path, refusing to overwrite it.
+func writeNew(path string, content []byte, stderr io.Writer) int {
+	if _, err := os.Stat(path); err == nil {
+		fmt.Fprintf(stderr, "baryon: %s already exists\n", path)
+		return 1
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runScaffold proposes Baryon Namespaces for the exported functions of the
+// R file at path, writing them in place, into output, or as a diff.
+func runScaffold(path, output string, diff bool, stdout, stderr io.Writer) int {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	c, err := findConfig(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	lines := strings.Split(string(in), "\n")
+	scaffolded, functions := scaffold(path, lines, rExported(path), c)
+	if len(functions) == 0 {
+		fmt.Fprintf(stderr, "baryon: %s: nothing to annotate\n", path)
+		return 0
+	}
+	out := []byte(strings.Join(scaffolded, "\n"))
+	if _, err := parser.NewRoxygenWithDefaults(c.defaults()).Parse(out); err != nil {
+		fmt.Fprintf(stderr, "baryon: warning: the scaffold of %s does not parse: %v\n", path, err)
+	}
+	switch {
+	case diff:
+		io.WriteString(stdout, unifiedDiff(path, path, lines, scaffolded))
+		return 0
+	case output != "":
+		return writeNew(output, out, stderr)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: %s\n", path, strings.Join(functions, ", "))
+	return 0
+}
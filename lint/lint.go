@@ -0,0 +1,179 @@
+// Package lint reports the suspicious annotations of a tool: the ones that
+// parse, but most likely do not produce the intended tool.
+package lint
+
+import (
+	"baryon/parser"
+	"baryon/tool"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity string
+
+const (
+	// Error is a tool that will not work as intended.
+	Error Severity = "error"
+	// Warning is a tool that is likely not to work as intended.
+	Warning Severity = "warning"
+	// Info is a tool that could be improved.
+	Info Severity = "info"
+)
+
+// Valid reports whether s is a known severity.
+func (s Severity) Valid() bool {
+	return s == Error || s == Warning || s == Info
+}
+
+// Source is what the rules check: a tool and the roxygen2 tags it was parsed
+// from.
+type Source struct {
+	// Tool is the parsed tool.
+	Tool *tool.Tool
+	// Entries are the tags of the source, empty for the JSON documents.
+	Entries []parser.Entry
+	// In is the source, to compute the lines of the entries.
+	In []byte
+}
+
+// Diagnostic is a finding of a Rule.
+type Diagnostic struct {
+	// Rule is the id of the rule.
+	Rule string
+	// Severity is the severity of the rule.
+	Severity Severity
+	// Message describes the finding.
+	Message string
+	// Fix suggests a fix, empty when there is none.
+	Fix string
+	// Param is the name of the param the finding is about, if any.
+	Param string
+	// Start and End are the offsets in the source the finding is about,
+	// both 0 when it is about the tool as a whole.
+	Start, End int
+}
+
+// Rule checks a Source.
+type Rule struct {
+	// Id names the rule, in the configuration and the lint-ignore
+	// instructions.
+	Id string
+	// Severity is the default severity of the diagnostics of the rule.
+	Severity Severity
+	// Description describes what the rule reports.
+	Description string
+	// Check returns the diagnostics of s, their Rule and Severity unset.
+	Check func(s *Source) []Diagnostic
+}
+
+// Config enables the rules and sets their severity.
+type Config struct {
+	// Disable are the ids of the disabled rules.
+	Disable []string `yaml:"disable"`
+	// Severity overrides the severity of rules, by id.
+	Severity map[string]Severity `yaml:"severity"`
+}
+
+// Validate returns an error when c refers to an unknown rule or severity.
+func (c Config) Validate() error {
+	for _, id := range c.Disable {
+		if FindRule(id) == nil {
+			return fmt.Errorf("[lint.Config.Validate]: unknown rule %q", id)
+		}
+	}
+	for id, severity := range c.Severity {
+		if FindRule(id) == nil {
+			return fmt.Errorf("[lint.Config.Validate]: unknown rule %q", id)
+		}
+		if !severity.Valid() {
+			return fmt.Errorf("[lint.Config.Validate]: unknown severity %q of rule %q", severity, id)
+		}
+	}
+	return nil
+}
+
+// FindRule returns the rule of id, or nil when there is none.
+func FindRule(id string) *Rule {
+	for i := range Rules {
+		if Rules[i].Id == id {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+// Lint returns the diagnostics of the rules enabled by c on s, sorted by
+// position. The diagnostics ignored by a lint-ignore instruction are
+// dropped: in a param tag, it ignores the diagnostics of the param, and in
+// another tag, all the diagnostics of the tool. Without arguments, it
+// ignores all the rules.
+func Lint(s *Source, c Config) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, rule := range Rules {
+		if slices.Contains(c.Disable, rule.Id) {
+			continue
+		}
+		severity := rule.Severity
+		if override, ok := c.Severity[rule.Id]; ok {
+			severity = override
+		}
+		for _, diagnostic := range rule.Check(s) {
+			diagnostic.Rule, diagnostic.Severity = rule.Id, severity
+			if !s.ignored(diagnostic) {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Start < diagnostics[j].Start
+	})
+	return diagnostics
+}
+
+// ignored reports whether a lint-ignore instruction of s ignores d.
+func (s *Source) ignored(d Diagnostic) bool {
+	for _, entry := range s.Entries {
+		if entry.Namespace == nil || (entry.Tag == "param" && entry.Name != d.Param) {
+			continue
+		}
+		for _, instruction := range entry.Namespace.Instructions {
+			if instruction.Name != "lint-ignore" {
+				continue
+			}
+			if strings.TrimSpace(instruction.Args) == "" {
+				return true
+			}
+			for _, id := range strings.Split(instruction.Args, ",") {
+				if strings.TrimSpace(id) == d.Rule {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Line returns the line, starting at 1, of d in s, or 0 when d is about the
+// tool as a whole.
+func (s *Source) Line(d Diagnostic) int {
+	if d.Start == 0 && d.End == 0 {
+		return 0
+	}
+	line, _ := parser.Position(s.In, d.Start)
+	return line
+}
+
+// Failing returns the diagnostics failing a check: the errors, and the
+// warnings when strict.
+func Failing(diagnostics []Diagnostic, strict bool) []Diagnostic {
+	failing := []Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == Error || (strict && diagnostic.Severity == Warning) {
+			failing = append(failing, diagnostic)
+		}
+	}
+	return failing
+}
@@ -0,0 +1,86 @@
+package lint
+
+import (
+	"baryon/parser"
+	"slices"
+	"testing"
+)
+
+// source parses the source in.
+func source(t *testing.T, in string) *Source {
+	t.Helper()
+	parsed, err := parser.NewRoxygen().Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	return &Source{Tool: parsed, Entries: parser.Entries([]byte(in)), In: []byte(in)}
+}
+
+// rules returns the rules of diagnostics.
+func rules(diagnostics []Diagnostic) []string {
+	ids := []string{}
+	for _, diagnostic := range diagnostics {
+		ids = append(ids, diagnostic.Rule)
+	}
+	return ids
+}
+
+func Test_Lint(t *testing.T) {
+	s := source(t, `#' @description Count $B{container(lab/wc);command(wc -l $input);id(count lines);name(Count)}
+#' @param input the file to count $B{type(data);!}
+#' @param unused an unused parameter $B{type(integer);value(1)}
+#' @param n number $B{type(integer);value(1);argument(-n)}
+#' @param undocumented a parameter without namespace
+`)
+	diagnostics := Lint(s, Config{})
+	want := []string{"invalid-id", "latest-tag", "missing-return-data", "unused-param", "short-help", "missing-namespace"}
+	if got := rules(diagnostics); !slices.Equal(got, want) {
+		t.Fatalf("Got rules %v, want %v", got, want)
+	}
+	if diagnostics[0].Fix != "use id(count_lines)" {
+		t.Errorf("Got fix %q", diagnostics[0].Fix)
+	}
+	if line := s.Line(diagnostics[3]); line != 3 || diagnostics[3].Param != "unused" {
+		t.Errorf("Got line %d of %+v", line, diagnostics[3])
+	}
+	if failing := Failing(diagnostics, false); len(failing) != 1 || failing[0].Rule != "invalid-id" {
+		t.Errorf("Got failing %+v", failing)
+	}
+	if failing := Failing(diagnostics, true); len(failing) != 5 {
+		t.Errorf("Got failing %+v", failing)
+	}
+
+	config := Config{Disable: []string{"invalid-id"}, Severity: map[string]Severity{"short-help": Error}}
+	diagnostics = Lint(s, config)
+	if failing := Failing(diagnostics, false); len(failing) != 1 || failing[0].Rule != "short-help" {
+		t.Errorf("Got failing %+v", failing)
+	}
+}
+
+func Test_Lint_ignore(t *testing.T) {
+	s := source(t, `#' @description Count $B{container(lab/wc:1.0);command(wc -l);id(count);name(Count);lint-ignore(missing-return-data)}
+#' @param unused an unused parameter $B{type(integer);value(1);lint-ignore(unused-param, short-help)}
+#' @param other an unused parameter $B{type(integer);value(1)}
+`)
+	diagnostics := Lint(s, Config{})
+	if len(diagnostics) != 1 || diagnostics[0].Rule != "unused-param" || diagnostics[0].Param != "other" {
+		t.Errorf("Got diagnostics %+v", diagnostics)
+	}
+
+	s = source(t, `#' @description Count $B{command(wc -l);lint-ignore}`)
+	if diagnostics := Lint(s, Config{}); len(diagnostics) != 0 {
+		t.Errorf("Got diagnostics %+v", diagnostics)
+	}
+}
+
+func Test_Config_Validate(t *testing.T) {
+	if err := (Config{Disable: []string{"short-help"}}).Validate(); err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+	if err := (Config{Disable: []string{"nope"}}).Validate(); err == nil {
+		t.Errorf("Expected error for an unknown rule.")
+	}
+	if err := (Config{Severity: map[string]Severity{"short-help": "fatal"}}).Validate(); err == nil {
+		t.Errorf("Expected error for an unknown severity.")
+	}
+}
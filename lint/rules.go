@@ -0,0 +1,265 @@
+package lint
+
+import (
+	"baryon/marshaler"
+	"baryon/parser"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// minHelpLength is the number of characters under which the help of a param
+// is too short.
+const minHelpLength = 10
+
+// idRegex matches the ids of Galaxy tools.
+var idRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// invalidIdRegex matches the characters not allowed in ids.
+var invalidIdRegex = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Rules are the rules of the linter, in the order they are run.
+var Rules = []Rule{
+	{
+		Id:          "invalid-id",
+		Severity:    Error,
+		Description: "the tool has no id, or an id with other characters than letters, digits, _ and -",
+		Check:       checkId,
+	},
+	{
+		Id:          "missing-name",
+		Severity:    Warning,
+		Description: "the tool has no name",
+		Check: func(s *Source) []Diagnostic {
+			if s.Tool.Name != "" {
+				return nil
+			}
+			name := s.Tool.Id
+			if name == "" {
+				name = "<name>"
+			}
+			return []Diagnostic{s.toolDiagnostic("the tool has no name",
+				fmt.Sprintf("add name(%s) to the Baryon Namespace of @description", name))}
+		},
+	},
+	{
+		Id:          "missing-description",
+		Severity:    Warning,
+		Description: "the tool has no description",
+		Check: func(s *Source) []Diagnostic {
+			if strings.TrimSpace(s.Tool.Description) != "" {
+				return nil
+			}
+			return []Diagnostic{s.toolDiagnostic("the tool has no description",
+				"describe the tool in @description")}
+		},
+	},
+	{
+		Id:          "missing-container",
+		Severity:    Warning,
+		Description: "the tool has no container",
+		Check: func(s *Source) []Diagnostic {
+			if s.Tool.Requirements != nil && len(s.Tool.Requirements.Container) > 0 {
+				return nil
+			}
+			return []Diagnostic{s.toolDiagnostic("the tool has no container",
+				"add container(<image>:<version>) to the Baryon Namespace of @description")}
+		},
+	},
+	{
+		Id:          "missing-command",
+		Severity:    Error,
+		Description: "the tool has no command",
+		Check: func(s *Source) []Diagnostic {
+			if s.Tool.Command != nil && strings.TrimSpace(s.Tool.Command.Value) != "" {
+				return nil
+			}
+			return []Diagnostic{s.toolDiagnostic("the tool has no command",
+				"add command(...) to the Baryon Namespace of @description")}
+		},
+	},
+	{
+		Id:          "latest-tag",
+		Severity:    Warning,
+		Description: "a docker container has no tag, or the latest tag, and is not reproducible",
+		Check:       checkLatestTag,
+	},
+	{
+		Id:          "missing-return-data",
+		Severity:    Warning,
+		Description: "the tool has no output",
+		Check:       checkReturnData,
+	},
+	{
+		Id:          "missing-namespace",
+		Severity:    Warning,
+		Description: "a @param has no Baryon Namespace, and is not an input of the tool",
+		Check:       checkNamespaces,
+	},
+	{
+		Id:          "unused-param",
+		Severity:    Warning,
+		Description: "a param is neither referenced by the command nor given an argument",
+		Check:       checkUnusedParams,
+	},
+	{
+		Id:          "short-help",
+		Severity:    Info,
+		Description: fmt.Sprintf("the help of a param is shorter than %d characters", minHelpLength),
+		Check:       checkShortHelp,
+	},
+}
+
+// entry returns the first entry of s with tag, and for a param tag, name.
+func (s *Source) entry(tag string, name string) *parser.Entry {
+	for i, entry := range s.Entries {
+		if entry.Tag == tag && (tag != "param" || entry.Name == name) {
+			return &s.Entries[i]
+		}
+	}
+	return nil
+}
+
+// at returns d about the namespace of entry, or entry itself when it has
+// none. d is about the tool as a whole when entry is nil.
+func at(d Diagnostic, entry *parser.Entry) Diagnostic {
+	switch {
+	case entry == nil:
+	case entry.Namespace != nil:
+		d.Start, d.End = entry.Namespace.Start, entry.Namespace.End
+	default:
+		d.Start, d.End = entry.Start, entry.End
+	}
+	return d
+}
+
+// toolDiagnostic returns a Diagnostic about the description of the tool.
+func (s *Source) toolDiagnostic(message string, fix string) Diagnostic {
+	return at(Diagnostic{Message: message, Fix: fix}, s.entry("description", ""))
+}
+
+// checkId implements the invalid-id rule.
+func checkId(s *Source) []Diagnostic {
+	id := s.Tool.Id
+	if idRegex.MatchString(id) {
+		return nil
+	}
+	suggestion := id
+	if suggestion == "" {
+		suggestion = s.Tool.Name
+	}
+	suggestion = strings.Trim(invalidIdRegex.ReplaceAllString(suggestion, "_"), "_")
+	fix := ""
+	if suggestion != "" {
+		fix = fmt.Sprintf("use id(%s)", strings.ToLower(suggestion))
+	}
+	if id == "" {
+		if fix == "" {
+			fix = "add id(...) to the Baryon Namespace of @description"
+		}
+		return []Diagnostic{s.toolDiagnostic("the tool has no id", fix)}
+	}
+	return []Diagnostic{s.toolDiagnostic(
+		fmt.Sprintf("the id %q has other characters than letters, digits, _ and -", id), fix)}
+}
+
+// checkLatestTag implements the latest-tag rule.
+func checkLatestTag(s *Source) []Diagnostic {
+	if s.Tool.Requirements == nil {
+		return nil
+	}
+	diagnostics := []Diagnostic{}
+	for _, container := range s.Tool.Requirements.Container {
+		if container.Type != "docker" || strings.Contains(container.Value, "@") {
+			// Pinned by digest.
+			continue
+		}
+		image := container.Value
+		name := image[strings.LastIndex(image, "/")+1:]
+		repository, tag, ok := strings.Cut(name, ":")
+		if ok && tag != "latest" {
+			continue
+		}
+		image = strings.TrimSuffix(image, ":latest")
+		diagnostics = append(diagnostics, s.toolDiagnostic(
+			fmt.Sprintf("the image %s is not pinned to a version", container.Value),
+			fmt.Sprintf("pin the version of %s, as container(%s:<version>)", repository, image)))
+	}
+	return diagnostics
+}
+
+// checkReturnData implements the missing-return-data rule.
+func checkReturnData(s *Source) []Diagnostic {
+	if s.Tool.Outputs != nil && len(s.Tool.Outputs.Data) > 0 {
+		return nil
+	}
+	d := Diagnostic{
+		Message: "the tool has no output",
+		Fix:     "add a @return $B{data(<file>,<format>)} tag for each output",
+	}
+	if entry := s.entry("return", ""); entry != nil {
+		d.Fix = "add data(<file>,<format>) to the Baryon Namespace of @return"
+		return []Diagnostic{at(d, entry)}
+	}
+	return []Diagnostic{s.toolDiagnostic(d.Message, d.Fix)}
+}
+
+// checkNamespaces implements the missing-namespace rule.
+func checkNamespaces(s *Source) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for i, entry := range s.Entries {
+		if entry.Tag != "param" || entry.Namespace != nil || entry.Name == "" {
+			continue
+		}
+		diagnostics = append(diagnostics, at(Diagnostic{
+			Message: fmt.Sprintf("the parameter %s has no Baryon Namespace, and is not an input of the tool", entry.Name),
+			Fix:     "add $B{type(text)} to the @param, with the type of the parameter",
+			Param:   entry.Name,
+		}, &s.Entries[i]))
+	}
+	return diagnostics
+}
+
+// checkUnusedParams implements the unused-param rule.
+func checkUnusedParams(s *Source) []Diagnostic {
+	data, err := marshaler.NewTemplateData(s.Tool)
+	if err != nil {
+		// Reported by missing-command and missing-container.
+		return nil
+	}
+	diagnostics := []Diagnostic{}
+	for _, param := range data.Params {
+		if data.UsedParams[param.Name] || param.Argument != "" {
+			continue
+		}
+		diagnostics = append(diagnostics, at(Diagnostic{
+			Message: fmt.Sprintf("the parameter %s is not used by the command", param.Name),
+			Fix:     fmt.Sprintf("reference $%s in the command, or add argument(--%s) to the @param", param.Name, param.Name),
+			Param:   param.Name,
+		}, s.entry("param", param.Name)))
+	}
+	return diagnostics
+}
+
+// checkShortHelp implements the short-help rule.
+func checkShortHelp(s *Source) []Diagnostic {
+	if s.Tool.Inputs == nil {
+		return nil
+	}
+	diagnostics := []Diagnostic{}
+	for _, param := range s.Tool.Inputs.Param {
+		if utf8.RuneCountInString(strings.TrimSpace(param.Help)) >= minHelpLength {
+			continue
+		}
+		message := fmt.Sprintf("the help of the parameter %s is too short", param.Name)
+		if strings.TrimSpace(param.Help) == "" {
+			message = fmt.Sprintf("the parameter %s has no help", param.Name)
+		}
+		diagnostics = append(diagnostics, at(Diagnostic{
+			Message: message,
+			Param:   param.Name,
+		}, s.entry("param", param.Name)))
+	}
+	return diagnostics
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"baryon/lsp"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spec is the specification of the Baryon Namespaces, documenting the
+// instructions in the language server.
+//
+//go:embed spec/spec.md
+var spec string
+
+// runLsp implements the lsp command.
+func runLsp(name string, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(name, stderr)
+	if status, stop := parseFlags(flags, args); stop {
+		return status
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	server := &lsp.Server{
+		Spec: spec,
+		Settings: func(path string) (lsp.Settings, error) {
+			c, err := findConfig(path)
+			if err != nil {
+				return lsp.Settings{}, err
+			}
+			return lsp.Settings{Defaults: c.defaults(), Lint: c.Lint}, nil
+		},
+	}
+	if err := server.Serve(os.Stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "baryon: %v\n", err)
+		return 1
+	}
+	return 0
+}
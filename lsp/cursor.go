@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tagRegex matches the roxygen2 tags.
+var tagRegex = regexp.MustCompile(`@([[:alpha:]]+)`)
+
+// continuationRegex matches the roxygen2 prefixes of the lines after the
+// first of a Baryon Namespace.
+var continuationRegex = regexp.MustCompile(`\n#' ?`)
+
+// cursor is the place of an offset in a Baryon Namespace being written.
+type cursor struct {
+	// tag is the roxygen2 tag of the namespace.
+	tag string
+	// instruction is the name of the instruction of the offset, written up
+	// to the offset when the offset is not in its arguments.
+	instruction string
+	// args reports whether the offset is in the arguments of instruction.
+	args bool
+	// arg is the argument written up to the offset, when args is true.
+	arg string
+}
+
+// cursorAt returns the place of offset in text, reporting whether it is in
+// a Baryon Namespace, possibly not closed yet.
+func cursorAt(text string, offset int) (cursor, bool) {
+	before := text[:offset]
+	open := strings.LastIndex(before, "$B{")
+	if open < 0 {
+		return cursor{}, false
+	}
+	lineStart := strings.LastIndex(before[:open], "\n") + 1
+	written := before[open+len("$B{"):]
+	if !strings.HasPrefix(before[lineStart:], "#'") || strings.ContainsAny(written, "}@") {
+		return cursor{}, false
+	}
+	for _, line := range strings.Split(written, "\n")[1:] {
+		if !strings.HasPrefix(line, "#'") {
+			return cursor{}, false
+		}
+	}
+	tags := tagRegex.FindAllStringSubmatch(before[:open], -1)
+	if len(tags) == 0 {
+		return cursor{}, false
+	}
+	c := cursor{tag: tags[len(tags)-1][1]}
+	written = continuationRegex.ReplaceAllString(written, " ")
+	instruction := strings.TrimLeft(written[strings.LastIndex(written, ";")+1:], " \t")
+	name, args, ok := strings.Cut(instruction, "(")
+	switch {
+	case !ok:
+		c.instruction = instruction
+	case strings.Contains(args, ")"):
+		return cursor{}, false
+	default:
+		c.instruction, c.args = strings.TrimSpace(name), true
+		c.arg = strings.TrimLeft(args[strings.LastIndex(args, ",")+1:], " \t")
+	}
+	return c, true
+}
+
+// isInstructionByte reports whether c may be part of an instruction name.
+func isInstructionByte(c byte) bool {
+	return c == '!' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// wordAt returns the offsets of the instruction name around offset in text.
+func wordAt(text string, offset int) (start, end int) {
+	start, end = offset, offset
+	for start > 0 && isInstructionByte(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isInstructionByte(text[end]) {
+		end++
+	}
+	return start, end
+}
+
+// specSections returns the sections of the Markdown spec, by the title of
+// their "###" heading, without the heading.
+func specSections(spec string) map[string]string {
+	sections := map[string]string{}
+	title, section := "", []string{}
+	fenced := false
+	flush := func() {
+		if title != "" {
+			sections[title] = strings.TrimSpace(strings.Join(section, "\n"))
+		}
+	}
+	for _, line := range strings.Split(spec, "\n") {
+		if strings.HasPrefix(line, "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.HasPrefix(line, "#") {
+			flush()
+			title, section = "", []string{}
+			if heading, ok := strings.CutPrefix(line, "### "); ok {
+				title = strings.TrimSpace(heading)
+			}
+			continue
+		}
+		section = append(section, line)
+	}
+	flush()
+	return sections
+}
+
+// summary returns the first sentence of the Markdown doc.
+func summary(doc string) string {
+	paragraph, _, _ := strings.Cut(doc, "\n\n")
+	paragraph = strings.Join(strings.Fields(paragraph), " ")
+	if sentence, _, ok := strings.Cut(paragraph, ". "); ok {
+		return sentence + "."
+	}
+	return paragraph
+}
@@ -0,0 +1,486 @@
+// Package lsp is a Language Server Protocol server for the Baryon
+// Namespaces of R files: it completes and documents their instructions,
+// reports the errors of the parser and the findings of lint as the files
+// are edited, and previews the Galaxy tools they generate.
+package lsp
+
+import (
+	"baryon/lint"
+	"baryon/marshaler"
+	"baryon/parser"
+	"baryon/tool"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// previewCommand is the command of the code action previewing the Galaxy
+// tool of a document.
+const previewCommand = "baryon.preview"
+
+// Settings are the settings of a document, from the configuration of its
+// project.
+type Settings struct {
+	// Defaults are the defaults of the tool.
+	Defaults parser.Defaults
+	// Lint configures the rules of lint.
+	Lint lint.Config
+}
+
+// Server is a Language Server Protocol server. Its zero value serves the
+// documents without hover docs, with the default Settings.
+type Server struct {
+	// Spec is the Markdown specification of the instructions, whose "###"
+	// sections document the instructions of their title.
+	Spec string
+	// Settings returns the Settings of the file at path, nil for the
+	// default Settings.
+	Settings func(path string) (Settings, error)
+	// PreviewDir is the directory the previews are written to, a directory
+	// of the temporary directory when empty.
+	PreviewDir string
+
+	out             io.Writer
+	instructionDocs map[string]string
+	documents       map[string]string
+	requests        int
+	shutdown        bool
+}
+
+// errExit is returned by handle on the exit notification.
+var errExit = errors.New("exit")
+
+// Serve serves the client reading from r and writing to w until it exits,
+// returning an error when it exits without shutting the server down first.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	s.instructionDocs = specSections(s.Spec)
+	s.documents = map[string]string{}
+	in := bufio.NewReader(r)
+	for {
+		content, err := readMessage(in)
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("[Server.Serve]: the client closed the connection")
+		}
+		if err != nil {
+			return fmt.Errorf("[Server.Serve]: %v", err)
+		}
+		m := message{}
+		if err := json.Unmarshal(content, &m); err != nil {
+			if err := s.respondError(nil, codeParseError, err.Error()); err != nil {
+				return fmt.Errorf("[Server.Serve]: %v", err)
+			}
+			continue
+		}
+		if m.Method == "" {
+			// A response to a request of the server.
+			continue
+		}
+		result, err := s.safeHandle(m.Method, m.Params)
+		if errors.Is(err, errExit) {
+			if !s.shutdown {
+				return fmt.Errorf("[Server.Serve]: exit before shutdown")
+			}
+			return nil
+		}
+		var requestError *responseError
+		if m.ID == nil {
+			// The notifications with invalid params are ignored.
+			if err != nil && !errors.As(err, &requestError) {
+				return fmt.Errorf("[Server.Serve]: %v", err)
+			}
+			continue
+		}
+		switch {
+		case errors.As(err, &requestError):
+			err = s.respondError(m.ID, requestError.Code, requestError.Message)
+		case err != nil:
+			err = s.respondError(m.ID, codeInternalError, err.Error())
+		default:
+			err = writeMessage(s.out, response{JSONRPC: "2.0", ID: m.ID, Result: result})
+		}
+		if err != nil {
+			return fmt.Errorf("[Server.Serve]: %v", err)
+		}
+	}
+}
+
+// Error implements error.
+func (e *responseError) Error() string { return e.Message }
+
+// respondError responds to the request id with an error.
+func (s *Server) respondError(id *json.RawMessage, code int, message string) error {
+	return writeMessage(s.out, errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   responseError{Code: code, Message: message},
+	})
+}
+
+// notify sends a notification to the client.
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, request{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// request sends a request to the client, ignoring its response.
+func (s *Server) request(method string, params any) error {
+	s.requests++
+	return writeMessage(s.out, request{JSONRPC: "2.0", ID: s.requests, Method: method, Params: params})
+}
+
+// safeHandle is handle, turning its panics into internal errors so that a
+// document crashing the parser does not end the session.
+func (s *Server) safeHandle(method string, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &responseError{Code: codeInternalError, Message: fmt.Sprintf("%s: %v", method, r)}
+		}
+	}()
+	return s.handle(method, params)
+}
+
+// handle handles the request or notification method, returning its result.
+func (s *Server) handle(method string, params json.RawMessage) (any, error) {
+	decode := func(v any) error {
+		if err := json.Unmarshal(params, v); err != nil {
+			return &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   map[string]any{"openClose": true, "change": 1},
+				"completionProvider": map[string]any{"triggerCharacters": []string{"{", ";", "(", ","}},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+				"executeCommandProvider": map[string]any{
+					"commands": []string{previewCommand},
+				},
+			},
+			"serverInfo": map[string]any{"name": "baryon"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	case "textDocument/didOpen":
+		p := didOpenParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		s.documents[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didChange":
+		p := didChangeParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		s.documents[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		return nil, s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didClose":
+		p := didCloseParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		delete(s.documents, p.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/completion":
+		p := textDocumentPositionParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		text := s.documents[p.TextDocument.URI]
+		return s.complete(text, offset(text, p.Position)), nil
+	case "textDocument/hover":
+		p := textDocumentPositionParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		text := s.documents[p.TextDocument.URI]
+		if h := s.hover(text, offset(text, p.Position)); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/codeAction":
+		p := codeActionParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		actions := []codeAction{}
+		if _, err := s.parse(p.TextDocument.URI); err == nil {
+			actions = append(actions, codeAction{
+				Title: "Preview the Galaxy tool",
+				Kind:  "source",
+				Command: command{
+					Title:     "Preview the Galaxy tool",
+					Command:   previewCommand,
+					Arguments: []string{p.TextDocument.URI},
+				},
+			})
+		}
+		return actions, nil
+	case "workspace/executeCommand":
+		p := executeCommandParams{}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if p.Command != previewCommand || len(p.Arguments) != 1 {
+			return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown command %q", p.Command)}
+		}
+		return s.preview(p.Arguments[0])
+	}
+	if strings.HasPrefix(method, "$/") || method == "initialized" {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+// path returns the path of the file of the document uri, empty when it is
+// not a file.
+func path(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// settings returns the Settings of the document uri.
+func (s *Server) settings(uri string) (Settings, error) {
+	if s.Settings == nil {
+		return Settings{}, nil
+	}
+	return s.Settings(path(uri))
+}
+
+// parse parses the document uri.
+func (s *Server) parse(uri string) (*tool.Tool, error) {
+	settings, err := s.settings(uri)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewRoxygenWithDefaults(settings.Defaults).Parse([]byte(s.documents[uri]))
+}
+
+// publishDiagnostics sends the diagnostics of the document uri.
+func (s *Server) publishDiagnostics(uri string) error {
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: s.diagnostics(uri),
+	})
+}
+
+// diagnostics returns the errors of the parser and the findings of lint of
+// the document uri. The documents without roxygen2 tag have none, and a
+// panic of the parser or of lint is reported as an error of the document.
+func (s *Server) diagnostics(uri string) (diagnostics []Diagnostic) {
+	defer func() {
+		if r := recover(); r != nil {
+			diagnostics = []Diagnostic{{Severity: severityError, Source: "baryon", Message: fmt.Sprintf("internal error: %v", r)}}
+		}
+	}()
+	text := s.documents[uri]
+	in := []byte(text)
+	entries := parser.Entries(in)
+	if len(entries) == 0 {
+		return []Diagnostic{}
+	}
+	settings, err := s.settings(uri)
+	if err != nil {
+		return []Diagnostic{{Severity: severityError, Source: "baryon", Message: err.Error()}}
+	}
+	t, err := parser.NewRoxygenWithDefaults(settings.Defaults).Parse(in)
+	if err != nil {
+		return parseDiagnostics(text, entries, err)
+	}
+	diagnostics = []Diagnostic{}
+	source := &lint.Source{Tool: t, Entries: entries, In: in}
+	for _, d := range lint.Lint(source, settings.Lint) {
+		message := d.Message
+		if d.Fix != "" {
+			message += "; " + d.Fix
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: position(text, d.Start), End: position(text, d.End)},
+			Severity: map[lint.Severity]int{lint.Error: severityError, lint.Warning: severityWarning, lint.Info: severityInformation}[d.Severity],
+			Code:     d.Rule,
+			Source:   "baryon",
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// errorPrefixRegex matches the prefixes of the errors of the parser naming
+// the functions they went through.
+var errorPrefixRegex = regexp.MustCompile(`^(?:\[[^\]]+\]|[A-Za-z]+(?:\["[a-z]+"\])?): `)
+
+// parseDiagnostics returns the diagnostics of the error err of the parser
+// on text: the tags failing to parse by themselves, or the first line of
+// text when none does.
+func parseDiagnostics(text string, entries []parser.Entry, err error) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, entry := range entries {
+		if entry.Namespace == nil {
+			continue
+		}
+		_, entryErr := parser.NewRoxygen().Parse([]byte("#' " + text[entry.Start:entry.End]))
+		if entryErr == nil {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: position(text, entry.Namespace.Start),
+				End:   position(text, entry.Namespace.End),
+			},
+			Severity: severityError,
+			Code:     "parse",
+			Source:   "baryon",
+			Message:  parseMessage(entryErr),
+		})
+	}
+	if len(diagnostics) > 0 {
+		return diagnostics
+	}
+	return []Diagnostic{{Severity: severityError, Code: "parse", Source: "baryon", Message: parseMessage(err)}}
+}
+
+// parseMessage returns the message of the error of the parser, without the
+// names of the functions it went through.
+func parseMessage(err error) string {
+	message := err.Error()
+	for {
+		trimmed := errorPrefixRegex.ReplaceAllString(message, "")
+		if trimmed == message {
+			return message
+		}
+		message = trimmed
+	}
+}
+
+// complete returns the completions at offset in text: the instructions of
+// the tag of the namespace, the types in type and the rules in
+// lint-ignore.
+func (s *Server) complete(text string, offset int) []CompletionItem {
+	items := []CompletionItem{}
+	c, ok := cursorAt(text, offset)
+	if !ok {
+		return items
+	}
+	instructions := parser.Instructions(c.tag)
+	if !c.args {
+		for _, name := range instructions {
+			if !strings.HasPrefix(name, c.instruction) {
+				continue
+			}
+			item := CompletionItem{Label: name, Kind: completionKeyword}
+			if doc := s.doc(name); doc != "" {
+				item.Detail = summary(doc)
+				item.Documentation = &markupContent{Kind: "markdown", Value: doc}
+			}
+			if name != "!" && name != "required" {
+				item.InsertText, item.InsertTextFormat = name+"($1)", snippetFormat
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+	if !slices.Contains(instructions, c.instruction) {
+		return items
+	}
+	switch c.instruction {
+	case "type":
+		for _, paramType := range tool.ParamTypes {
+			if strings.HasPrefix(paramType, c.arg) {
+				items = append(items, CompletionItem{Label: paramType, Kind: completionValue})
+			}
+		}
+	case "lint-ignore":
+		for _, rule := range lint.Rules {
+			if strings.HasPrefix(rule.Id, c.arg) {
+				items = append(items, CompletionItem{Label: rule.Id, Kind: completionValue, Detail: rule.Description})
+			}
+		}
+	}
+	return items
+}
+
+// doc returns the doc of the instruction name, empty when there is none.
+func (s *Server) doc(name string) string {
+	if name == "!" {
+		name = "required"
+	}
+	return s.instructionDocs[name]
+}
+
+// hover returns the doc of the instruction at offset in text, nil when
+// there is none.
+func (s *Server) hover(text string, offset int) *hover {
+	start, end := wordAt(text, offset)
+	c, ok := cursorAt(text, start)
+	if !ok || c.args || start == end {
+		return nil
+	}
+	name := text[start:end]
+	doc := s.doc(name)
+	if doc == "" || !slices.Contains(parser.Instructions(c.tag), name) {
+		return nil
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: doc},
+		Range:    Range{Start: position(text, start), End: position(text, end)},
+	}
+}
+
+// preview writes the Galaxy tool of the document uri to the PreviewDir,
+// asks the client to show it, and returns it.
+func (s *Server) preview(uri string) (string, error) {
+	t, err := s.parse(uri)
+	if err != nil {
+		return "", err
+	}
+	out, err := marshaler.GalaxyMarshaler{}.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	dir := s.PreviewDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "baryon-preview")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := t.Id
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path(uri)), filepath.Ext(path(uri)))
+	}
+	file, err := filepath.Abs(filepath.Join(dir, name+".xml"))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, out, 0644); err != nil {
+		return "", err
+	}
+	fileURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()
+	if err := s.request("window/showDocument", showDocumentParams{URI: fileURI, TakeFocus: true}); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
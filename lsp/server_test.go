@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// client is a client of a Server, for the tests.
+type client struct {
+	t      *testing.T
+	in     io.Writer
+	out    *bufio.Reader
+	served chan error
+	id     int
+}
+
+// newClient returns a client of server.
+func newClient(t *testing.T, server *Server) *client {
+	t.Helper()
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, out: bufio.NewReader(outReader), served: make(chan error, 1)}
+	go func() {
+		c.served <- server.Serve(inReader, outWriter)
+		outWriter.Close()
+	}()
+	return c
+}
+
+// send sends a notification, or a request when id is true.
+func (c *client) send(method string, params any, id bool) {
+	c.t.Helper()
+	m := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id {
+		c.id++
+		m["id"] = c.id
+	}
+	if err := writeMessage(c.in, m); err != nil {
+		c.t.Fatalf("Got this error: %v", err)
+	}
+}
+
+// receive receives a message into v.
+func (c *client) receive(v any) {
+	c.t.Helper()
+	content, err := readMessage(c.out)
+	if err != nil {
+		c.t.Fatalf("Got this error: %v", err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		c.t.Fatalf("Got this error: %v", err)
+	}
+}
+
+// call sends a request and receives the result of its response into v.
+func (c *client) call(method string, params any, v any) {
+	c.t.Helper()
+	c.send(method, params, true)
+	r := struct {
+		Result json.RawMessage
+		Error  *responseError
+	}{}
+	c.receive(&r)
+	if r.Error != nil {
+		c.t.Fatalf("Got this error: %v", r.Error)
+	}
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		c.t.Fatalf("Got this error: %v", err)
+	}
+}
+
+func Test_Server(t *testing.T) {
+	server := &Server{
+		Spec:       "# Spec\n\n### type\n\n`type` tags a parameter with its type.\n\n```\n# not a heading\n```\n## Other\n",
+		PreviewDir: t.TempDir(),
+	}
+	c := newClient(t, server)
+	c.call("initialize", map[string]any{}, &map[string]any{})
+	c.send("initialized", map[string]any{}, false)
+
+	uri := "file:///lab/count.R"
+	text := `#' @description Count $B{container(lab/wc:1.0);command(wc -l $input $n);id(count);name(Count)}
+#' @param input the file $B{type(data);!}
+#' @param n the number $B{type(integer);vaule(1)}
+#' @return $B{data(out.txt,txt);lint-ignore(sh
+`
+	c.send("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": text}}, false)
+	published := struct{ Params publishDiagnosticsParams }{}
+	c.receive(&published)
+	diagnostics := published.Params.Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start != (Position{2, 23}) || diagnostics[0].Message != `option "vaule" not found.` {
+		t.Errorf("Got diagnostics %+v", diagnostics)
+	}
+
+	complete := func(line, character int) []string {
+		t.Helper()
+		items := []CompletionItem{}
+		position := map[string]any{"line": line, "character": character}
+		c.call("textDocument/completion", map[string]any{"textDocument": map[string]any{"uri": uri}, "position": position}, &items)
+		labels := []string{}
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+	if got := complete(2, 42); !slices.Equal(got, []string{"value"}) {
+		t.Errorf("Got completions %v", got)
+	}
+	if got := complete(1, 34); !slices.Equal(got, []string{"data_column", "data", "data_collection", "drill_down"}) {
+		t.Errorf("Got completions %v", got)
+	}
+	if got := complete(3, 46); !slices.Equal(got, []string{"short-help"}) {
+		t.Errorf("Got completions %v", got)
+	}
+	if got := complete(0, 10); len(got) != 0 {
+		t.Errorf("Got completions %v outside a namespace", got)
+	}
+
+	h := &hover{}
+	c.call("textDocument/hover", map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": 1, "character": 28}}, &h)
+	if h == nil || h.Contents.Value != "`type` tags a parameter with its type.\n\n```\n# not a heading\n```" || h.Range.Start != (Position{1, 28}) {
+		t.Errorf("Got hover %+v", h)
+	}
+
+	text = strings.Replace(text, "vaule", "value", 1)
+	text = strings.Replace(text, "lint-ignore(sh\n", "lint-ignore(short-help)}\n", 1)
+	c.send("textDocument/didChange", map[string]any{"textDocument": map[string]any{"uri": uri}, "contentChanges": []map[string]any{{"text": text}}}, false)
+	c.receive(&published)
+	if len(published.Params.Diagnostics) != 0 {
+		t.Errorf("Got diagnostics %+v", published.Params.Diagnostics)
+	}
+
+	actions := []codeAction{}
+	c.call("textDocument/codeAction", map[string]any{"textDocument": map[string]any{"uri": uri}}, &actions)
+	if len(actions) != 1 || actions[0].Command.Command != previewCommand {
+		t.Fatalf("Got code actions %+v", actions)
+	}
+	c.send("workspace/executeCommand", actions[0].Command, true)
+	show := struct{ Method string }{}
+	c.receive(&show)
+	if show.Method != "window/showDocument" {
+		t.Errorf("Got request %q", show.Method)
+	}
+	preview := struct{ Result string }{}
+	c.receive(&preview)
+	if !strings.HasPrefix(preview.Result, `<tool id="count" name="Count">`) {
+		t.Errorf("Got preview %q", preview.Result)
+	}
+
+	c.call("shutdown", nil, &map[string]any{})
+	c.send("exit", nil, false)
+	if err := <-c.served; err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+}
+
+// Test_Server_volumeBeforeContainer checks that a volume before any
+// container is reported, instead of ending the session.
+func Test_Server_volumeBeforeContainer(t *testing.T) {
+	c := newClient(t, &Server{})
+	c.call("initialize", map[string]any{}, &map[string]any{})
+	uri := "file:///lab/count.R"
+	text := "#' @description Count $B{volume(a:/b);container(lab/wc:1.0);id(count)}\n"
+	c.send("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": text}}, false)
+	published := struct{ Params publishDiagnosticsParams }{}
+	c.receive(&published)
+	if diagnostics := published.Params.Diagnostics; len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "there's no container") {
+		t.Errorf("Got diagnostics %+v", diagnostics)
+	}
+	c.call("shutdown", nil, &map[string]any{})
+	c.send("exit", nil, false)
+	if err := <-c.served; err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+}
+
+func Test_position(t *testing.T) {
+	text := "#' é\n#' 😀x"
+	for _, want := range []Position{{0, 0}, {0, 4}, {1, 3}, {1, 5}} {
+		if got := position(text, offset(text, want)); got != want {
+			t.Errorf("Got %+v, want %+v", got, want)
+		}
+	}
+	if got := position(text, len(text)); got != (Position{1, 6}) {
+		t.Errorf("Got %+v", got)
+	}
+}
@@ -3,61 +3,288 @@ package main
 import (
 	"baryon/marshaler"
 	"baryon/parser"
-	"encoding/xml"
+	"baryon/tool"
+	"bytes"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// command is a subcommand of baryon.
+type command struct {
+	// usage is the synopsis of the command, after its name.
+	usage string
+	// summary is the line of the command in the help of baryon.
+	summary string
+	// description is the help of the command.
+	description string
+	// run runs the command with its arguments, returning the exit status.
+	run func(name string, args []string, stdout, stderr io.Writer) int
+}
+
+// commandNames are the names of the commands, in the order of the help.
+var commandNames = []string{"generate", "validate", "lint", "fmt", "inspect", "diff", "init", "lsp"}
+
+// commands are the commands of baryon, by name. It is filled by init to
+// break the initialization cycle with runHelp.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"generate": {
+			usage:   "[flags] file...",
+			summary: "generate a tool in a format",
+			description: "Generate a tool in the format given by --format, from an R file annotated\n" +
+				"with Baryon namespaces or from its JSON representation. Several files or\n" +
+				"directories generate all their tools.",
+			run: runGenerate,
+		},
+		"validate": {
+			usage:   "[flags] file...",
+			summary: "check that tools can be generated",
+			description: "Check that the tools parse and can be generated, printing one line per\n" +
+				"file.",
+			run: runValidate,
+		},
+		"lint": {
+			usage:       "[flags] file...",
+			summary:     "report suspicious annotations",
+			description: "Report the suspicious annotations of the tools.",
+			run:         runLint,
+		},
+		"fmt": {
+			usage:   "[flags] [file...]",
+			summary: "format the Baryon namespaces of R files",
+			description: "Rewrite the Baryon namespaces of R files in their canonical layout,\n" +
+				"leaving the rest of the files untouched, and print the files changed.\n" +
+				"Without file, format the standard input to the standard output.",
+			run: runFmt,
+		},
+		"inspect": {
+			usage:       "[flags] file",
+			summary:     "print a parsed tool",
+			description: "Print the tool parsed from a file.",
+			run:         runInspect,
+		},
+		"diff": {
+			usage:   "[flags] old new",
+			summary: "compare two versions of a tool",
+			description: "Compare the params, outputs and containers of two versions of a tool, R\n" +
+				"files or tool XMLs, classifying each change as breaking or not and\n" +
+				"suggesting the next version of the tool. Exit with status 1 on breaking\n" +
+				"changes.",
+			run: runDiff,
+		},
+		"init": {
+			usage:   "[flags] name|file",
+			summary: "write a skeleton, or annotate an R file",
+			description: "Write an annotated R function skeleton for a new tool, or propose Baryon\n" +
+				"namespaces for the exported functions of an existing R file.",
+			run: runInit,
+		},
+		"lsp": {
+			usage:   "",
+			summary: "run the language server",
+			description: "Run a Language Server Protocol server over the standard input and output,\n" +
+				"completing and documenting the instructions of the Baryon namespaces,\n" +
+				"reporting their errors and lint findings, and previewing the Galaxy tools.",
+			run: runLsp,
+		},
+		"help": {
+			usage:       "[command]",
+			summary:     "print the help of a command",
+			description: "Print the help of baryon, or of a command.",
+			run:         runHelp,
+		},
+	}
+}
+
 func main() {
-	argsWithoutProg := os.Args[1:]
-	var filePath string
-	if len(argsWithoutProg) > 0 {
-		filePath = argsWithoutProg[0]
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs baryon with args, returning the exit status: 0 on success, 1 on
+// failure and 2 on usage errors.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
 	}
-	parser := parser.NewRoxygen()
-	file, err := getFile(filePath)
-	if err != nil {
-		log.Fatal(err)
+	name := args[0]
+	switch name {
+	case "-h", "-help", "--help":
+		printUsage(stdout)
+		return 0
+	}
+	if command, ok := commands[name]; ok {
+		return command.run(name, args[1:], stdout, stderr)
+	}
+	// Former invocation: baryon [flags] file [format].
+	return runLegacy(args, stdout, stderr)
+}
+
+// printUsage prints the help of baryon.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Baryon builds Galaxy tools, scripts and packages from annotated R functions.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: baryon <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range append(commandNames, "help") {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "baryon help <command>" for the help of a command.`)
+}
+
+// runHelp implements the help command.
+func runHelp(name string, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stdout)
+		return 0
+	}
+	if _, ok := commands[args[0]]; !ok {
+		fmt.Fprintf(stderr, "baryon: unknown command %q\n", args[0])
+		return 2
+	}
+	return commands[args[0]].run(args[0], []string{"-h"}, stdout, stderr)
+}
+
+// newFlagSet returns the flag set of the command name, printing its help to
+// stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		usage := strings.TrimSpace(name + " " + commands[name].usage)
+		fmt.Fprintf(stderr, "Usage: baryon %s\n\n%s\n", usage, commands[name].description)
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(stderr, "\nFlags:")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+// parseFlags parses args into flags, returning the exit status when the
+// command must stop: 0 when the help was asked, 2 on usage errors.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, true
+		}
+		return 2, true
 	}
-	stat, err := file.Stat()
+	return 0, false
+}
+
+// parserFlag adds the --parser flag to flags.
+func parserFlag(flags *flag.FlagSet) *string {
+	parserName := new(string)
+	parserFlagVar(flags, parserName)
+	return parserName
+}
+
+// parserFlagVar adds the --parser flag to flags, stored in parserName.
+func parserFlagVar(flags *flag.FlagSet, parserName *string) {
+	flags.StringVar(parserName, "parser", "auto",
+		"parser of the files: roxygen, json, galaxy, or auto to choose json for the files starting with { and galaxy for the ones starting with <")
+}
+
+// writeFiles writes files under the directory dir, creating it.
+func writeFiles(dir string, files []marshaler.File) error {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, file.Content, file.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceFile is a parsed file.
+type sourceFile struct {
+	// path of the file, empty for the standard input.
+	path string
+	// in is the content of the file.
+	in []byte
+	// tool is the parsed tool.
+	tool *tool.Tool
+	// config is the configuration of the file.
+	config *config
+}
+
+// readSource parses the tool of the file at path, or of the standard input
+// when path is empty, with the parser named parserName and the defaults of
+// the configuration of the file.
+func readSource(path string, parserName string) (*sourceFile, error) {
+	c, err := findConfig(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	if stat.Size() == 0 {
-		log.Fatal("No file provided.")
+	file, err := getFile(path)
+	if err != nil {
+		return nil, err
 	}
 	defer file.Close()
 	fileread, err := io.ReadAll(file)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if len(fileread) == 0 {
+		return nil, fmt.Errorf("No file provided.")
+	}
+	selected, err := selectParser(parserName, fileread, filepath.Dir(path), c.defaults())
+	if err != nil {
+		return nil, err
+	}
+	t, err := selected.Parse(fileread)
+	if err != nil {
+		return nil, err
 	}
-	tool, err := parser.Parse(fileread)
+	return &sourceFile{path: path, in: fileread, tool: t, config: c}, nil
+}
+
+// parseFile parses the tool of the file at path, as readSource.
+func parseFile(path string, parserName string) (*tool.Tool, error) {
+	source, err := readSource(path, parserName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	output, err := func() ([]byte, error) {
-		var mode string
-		if len(argsWithoutProg) > 1 {
-			mode = argsWithoutProg[1]
+	return source.tool, nil
+}
+
+// selectParser returns the parser named name. The auto parser is the JSON
+// parser when in is a JSON document, as produced by the json format, the
+// galaxy parser when it is an XML document, reading the macros files in
+// dir, and the roxygen parser otherwise. The roxygen parser applies
+// defaults, while JSON and XML documents are complete.
+func selectParser(name string, in []byte, dir string, defaults parser.Defaults) (parser.Parser, error) {
+	switch name {
+	case "roxygen":
+		return parser.NewRoxygenWithDefaults(defaults), nil
+	case "json":
+		return parser.NewJSON(), nil
+	case "galaxy":
+		return parser.NewGalaxy(dir), nil
+	case "", "auto":
+		if bytes.HasPrefix(bytes.TrimSpace(in), []byte("{")) {
+			return parser.NewJSON(), nil
 		}
-		switch mode {
-		case "bash":
-			out, err := marshaler.BashMarshaler{}.Marshal(tool)
-			return out, err
-		case "python":
-			out, err := marshaler.PythonMarshaler{}.Marshal(tool)
-			return out, err
-		default:
-			out, err := xml.MarshalIndent(tool, "", "\t")
-			return out, err
+		if bytes.HasPrefix(bytes.TrimSpace(in), []byte("<")) {
+			return parser.NewGalaxy(dir), nil
 		}
-	}()
-	if err != nil {
-		log.Fatal(err)
+		return parser.NewRoxygenWithDefaults(defaults), nil
 	}
-	fmt.Print(string(output))
+	return nil, fmt.Errorf("unknown parser %q; available parsers: auto, galaxy, json, roxygen", name)
 }
 
 // getFile retrieves a *os.File if a path is provided and is not empty.
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_getFile(t *testing.T) {
@@ -30,3 +35,470 @@ func Test_getFile(t *testing.T) {
 		t.Fatal("Got wrong file:", a.Name())
 	}
 }
+
+func Test_runGenerate(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", "--format", "galaxy", "test_assets/16s.R"}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	if !strings.Contains(stdout.String(), `<tool id="16s" name="16s">`) {
+		t.Errorf("Got output:\n%s", stdout)
+	}
+
+	dir := t.TempDir()
+	args := []string{"generate", "--format", "bash", "--output-dir", dir, "test_assets/16s.R"}
+	if status := run(args, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	if _, err := os.Stat(path.Join(dir, "16s.sh")); err != nil {
+		t.Errorf("Got error %v", err)
+	}
+}
+
+func Test_runGenerate_unknownFormat(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", "--format", "nope", "test_assets/16s.R"}, stdout, stderr); status != 1 {
+		t.Fatalf("Got status %d", status)
+	}
+	if !strings.Contains(stderr.String(), `unknown format "nope"; available formats: bash,`) {
+		t.Errorf("Got error %q", stderr)
+	}
+}
+
+func Test_runValidate(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"validate", "test_assets/16s.R"}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stdout)
+	}
+	if status := run([]string{"validate", "--strict", "test_assets/16s.R"}, stdout, stderr); status != 1 {
+		t.Errorf("Got status %d with --strict", status)
+	}
+	if status := run([]string{"validate", "--parser", "nope", "test_assets/16s.R"}, stdout, stderr); status != 1 {
+		t.Errorf("Got status %d with an unknown parser", status)
+	}
+}
+
+func Test_runGenerate_batch(t *testing.T) {
+	dir := t.TempDir()
+	source, err := os.ReadFile("test_assets/16s.R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := strings.ReplaceAll(string(source), "id(16s)", "id(other)")
+	for name, content := range map[string]string{
+		"R/a.R":       string(source),
+		"R/b.R":       other,
+		"R/bad.R":     "print(1)\n",
+		"R/.hidden/c": "ignored",
+	} {
+		file := path.Join(dir, name)
+		os.MkdirAll(path.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := t.TempDir()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	args := []string{"generate", "--jobs", "2", "--output-dir", out, "--layout", "{name}/{id}{ext}", path.Join(dir, "R")}
+	if status := run(args, stdout, stderr); status != 1 {
+		t.Fatalf("Got status %d", status)
+	}
+	for _, name := range []string{"a/16s.xml", "b/other.xml"} {
+		if _, err := os.Stat(path.Join(out, name)); err != nil {
+			t.Errorf("Got error %v", err)
+		}
+	}
+	if !strings.HasSuffix(stdout.String(), "2 generated, 1 failed\n") {
+		t.Errorf("Got summary %q", stdout)
+	}
+	if !strings.HasPrefix(stderr.String(), path.Join(dir, "R/bad.R")+": ") {
+		t.Errorf("Got errors %q", stderr)
+	}
+}
+
+func Test_runGenerate_batchNoId(t *testing.T) {
+	dir := t.TempDir()
+	source, err := os.ReadFile("test_assets/test_function.R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.R", "b.R"} {
+		if err := os.WriteFile(path.Join(dir, name), source, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", "--output-dir", out, dir}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	for _, name := range []string{"a.xml", "b.xml"} {
+		if _, err := os.Stat(path.Join(out, name)); err != nil {
+			t.Errorf("Got error %v", err)
+		}
+	}
+	if _, err := os.Stat(path.Join(out, ".xml")); err == nil {
+		t.Errorf("Expected no hidden .xml file")
+	}
+}
+
+func Test_runGenerate_docsSite(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"DESCRIPTION": "Package: lab\n",
+		"baryon.yaml": "outputs:\n  docs: site/{id}{ext}\n",
+		"R/a.R":       "#' @description Counts things $B{id(count);name(Count)}\n",
+		"R/b.R":       "#' @description Sorts things $B{id(sort-lines);name(Sort)}\n",
+	} {
+		file := path.Join(dir, name)
+		os.MkdirAll(path.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", path.Join(dir, "R")}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	index, err := os.ReadFile(path.Join(dir, "site/index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"| [Count](count.md) | Counts things |", "| [Sort](sort_lines.md) | Sorts things |"} {
+		if !strings.Contains(string(index), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, index)
+		}
+	}
+	if _, err := os.Stat(path.Join(dir, "site/sort_lines.md")); err != nil {
+		t.Errorf("Got error %v", err)
+	}
+
+	out := t.TempDir()
+	if status := run([]string{"generate", "--format", "docs-html", "--output-dir", out, path.Join(dir, "R")}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	if _, err := os.Stat(path.Join(out, "index.html")); err != nil {
+		t.Errorf("Got error %v", err)
+	}
+}
+
+func Test_watch(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	source, err := os.ReadFile("test_assets/16s.R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := path.Join(dir, "a.R")
+	if err := os.WriteFile(file, source, 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := &generateOptions{format: "galaxy", outputDir: out, layout: defaultLayout, jobs: 1,
+		pollInterval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, options, []string{dir}, io.Discard, io.Discard)
+		close(done)
+	}()
+	waitFor := func(name string) {
+		for range 200 {
+			if _, err := os.Stat(path.Join(out, name)); err == nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%s not generated", name)
+	}
+	waitFor("16s.xml")
+	changed := strings.ReplaceAll(string(source), "id(16s)", "id(changed)")
+	if err := os.WriteFile(file, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("changed.xml")
+	cancel()
+	<-done
+}
+
+func Test_watch_config(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{
+		"DESCRIPTION": "Package: lab\n",
+		"baryon.yaml": "container: lab/r:4.4\nid_prefix: lab_\n",
+		"R/a.R":       "#' @description A tool $B{command(echo $n);id(a);name(A)}\n#' @param n a number $B{type(integer);value(1)}\n",
+	} {
+		file := path.Join(dir, name)
+		os.MkdirAll(path.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	options := &generateOptions{format: "galaxy", outputDir: out, layout: defaultLayout, jobs: 1,
+		pollInterval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, options, []string{path.Join(dir, "R")}, io.Discard, io.Discard)
+		close(done)
+	}()
+	waitFor := func(name string) {
+		for range 200 {
+			if _, err := os.Stat(path.Join(out, name)); err == nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%s not generated", name)
+	}
+	waitFor("lab_a.xml")
+	if err := os.WriteFile(path.Join(dir, "baryon.yaml"), []byte("container: lab/r:4.4\nid_prefix: other_\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("other_a.xml")
+	cancel()
+	<-done
+}
+
+func Test_runGenerate_config(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"DESCRIPTION": "Package: lab\n",
+		"baryon.yaml": "container: lab/r:4.4\nid_prefix: lab_\noutputs:\n  galaxy: galaxy/{id}.xml\n",
+		"R/a.R":       "#' @description A tool $B{command(echo $n);id(a);name(A)}\n#' @param n a number $B{type(integer);value(1)}\n",
+	} {
+		file := path.Join(dir, name)
+		os.MkdirAll(path.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", path.Join(dir, "R/a.R")}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	output, err := os.ReadFile(path.Join(dir, "galaxy/lab_a.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), `<container type="docker">lab/r:4.4</container>`) {
+		t.Errorf("Got output:\n%s", output)
+	}
+}
+
+func Test_runGenerate_owner(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"DESCRIPTION": "Package: lab\n",
+		"baryon.yaml": "container: lab/r:4.4\nowner: lab\n",
+		"R/a.R":       "#' @description A tool $B{command(echo $n);id(a);name(A)}\n#' @author Jane Doe\n#' @param n a number $B{type(integer);value(1)}\n",
+	} {
+		file := path.Join(dir, name)
+		os.MkdirAll(path.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, out := path.Join(dir, "R/a.R"), t.TempDir()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", "--format", "package", "--output-dir", out, file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	config, err := os.ReadFile(path.Join(out, ".shed.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "owner: lab\n") || stderr.Len() != 0 {
+		t.Errorf("Got .shed.yml:\n%s%s", config, stderr)
+	}
+
+	if err := os.WriteFile(path.Join(dir, "baryon.yaml"), []byte("container: lab/r:4.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := run([]string{"generate", "--format", "package", "--output-dir", out, file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	if config, err = os.ReadFile(path.Join(out, ".shed.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(config), "owner:") {
+		t.Errorf("Expected no owner in:\n%s", config)
+	}
+	if !strings.Contains(stderr.String(), "warning: the Tool Shed repository has no owner") {
+		t.Errorf("Expected a warning, got:\n%s", stderr)
+	}
+}
+
+func Test_runGenerate_suiteNoPackage(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	source, err := os.ReadFile("test_assets/16s.R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := path.Join(dir, "a.R")
+	if err := os.WriteFile(file, source, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"generate", "--suite", "lab", "--output-dir", out, file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	toolConf, err := os.ReadFile(path.Join(out, "tool_conf.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "<section id=\"lab\" name=\"lab\">\n\t\t<tool file=\"16s/16s.xml\"></tool>\n\t</section>"; !strings.Contains(string(toolConf), expect) {
+		t.Errorf("Expected %q in:\n%s", expect, toolConf)
+	}
+}
+
+func Test_runFmt(t *testing.T) {
+	file := path.Join(t.TempDir(), "a.R")
+	in := "#' @param n a number $B{value(1);type(integer);}\nf <- function(n) n\n"
+	if err := os.WriteFile(file, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"fmt", "--check", file}, stdout, stderr); status != 1 || stdout.String() != file+"\n" {
+		t.Fatalf("Got status %d: %s%s", status, stdout, stderr)
+	}
+	if status := run([]string{"fmt", file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	out, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#' @param n a number $B{type(integer);value(1)}\nf <- function(n) n\n"; string(out) != want {
+		t.Errorf("Got formatted file:\n%s", out)
+	}
+	stdout.Reset()
+	if status := run([]string{"fmt", "--check", file}, stdout, stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("Got status %d: %s", status, stdout)
+	}
+}
+
+func Test_inferParam(t *testing.T) {
+	tests := map[string]string{
+		"input":              "!;type(data)",
+		"n = 3L":             "type(integer);value(3)",
+		"ratio = 0.5":        "type(float);value(0.5)",
+		"verbose = FALSE":    "type(boolean);value(false)",
+		`label = "reads"`:    "type(text);value(reads)",
+		`mode = c("a", 'b')`: "type(select);value(a);options(a,b)",
+		"seed = NULL":        "!;type(text)",
+		"f = function(x) x":  "!;type(text)",
+	}
+	for argument, want := range tests {
+		instructions := []string{}
+		for _, instruction := range inferParam(rArguments(argument + ")")[0]) {
+			if instruction.Args == "" {
+				instructions = append(instructions, instruction.Name)
+			} else {
+				instructions = append(instructions, instruction.Name+"("+instruction.Args+")")
+			}
+		}
+		if got := strings.Join(instructions, ";"); got != want {
+			t.Errorf("Got %q for %q, want %q", got, argument, want)
+		}
+	}
+}
+
+func Test_runInit_scaffold(t *testing.T) {
+	file := path.Join(t.TempDir(), "count.R")
+	in := `#' Count lines
+#'
+#' @param input the file
+#' @export
+count <- function(input, n = 1L, # the number
+                  mode = c("fast", "exact")) {}
+`
+	if err := os.WriteFile(file, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"init", "--diff", file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	if diff := stdout.String(); !strings.Contains(diff, "-#' @param input the file\n") || !strings.Contains(diff, "+#' @param input the file $B{!;type(data)}\n") {
+		t.Errorf("Got diff:\n%s", stdout)
+	}
+	if status := run([]string{"init", file}, stdout, stderr); status != 0 {
+		t.Fatalf("Got status %d: %s", status, stderr)
+	}
+	tool, err := parseFile(file, "roxygen")
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if tool.Id != "count" || tool.Command.Value != "Rscript /scripts/count.R $input $n $mode" || len(tool.Inputs.Param) != 3 {
+		t.Errorf("Got tool %+v", tool)
+	}
+	if param := tool.Inputs.Param[2]; param.Type != "select" || param.Value != "fast" || len(param.Options) != 2 {
+		t.Errorf("Got param %+v", param)
+	}
+	stderr.Reset()
+	if status := run([]string{"init", file}, stdout, stderr); status != 0 || !strings.Contains(stderr.String(), "nothing to annotate") {
+		t.Errorf("Got status %d: %s", status, stderr)
+	}
+}
+
+func Test_runDiff(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"DESCRIPTION": "Package: count\nVersion: 1.2.3\n",
+		"old.R":       "#' @description Count $B{id(count);name(Count);command(wc -l $input $n)}\n#' @param input the file $B{!;type(data)}\n#' @param n a number $B{type(integer);value(1)}\n#' @return $B{data(out,txt)}\n",
+		"minor.R":     "#' @description Count $B{id(count);name(Count);command(wc -l $input $n $m)}\n#' @param input the file $B{!;type(data)}\n#' @param n a number $B{type(integer);value(1)}\n#' @param m a number $B{type(integer);value(2)}\n#' @return $B{data(out,txt)}\n",
+		"major.R":     "#' @description Count $B{id(count);name(Count);command(wc -l $input)}\n#' @param input the file $B{!;type(data)}\n#' @return $B{data(out,tabular)}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := path.Join(dir, "old.R")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"diff", old, old}, stdout, stderr); status != 0 || stdout.String() != "no change\n" {
+		t.Errorf("Got status %d: %s%s", status, stdout, stderr)
+	}
+	stdout.Reset()
+	if status := run([]string{"diff", old, path.Join(dir, "minor.R")}, stdout, stderr); status != 0 || !strings.Contains(stdout.String(), "minor version 1.3.0, from 1.2.3") {
+		t.Errorf("Got status %d: %s%s", status, stdout, stderr)
+	}
+	stdout.Reset()
+	if status := run([]string{"diff", "--version", "2.0", old, path.Join(dir, "major.R")}, stdout, stderr); status != 1 || !strings.Contains(stdout.String(), "2 breaking: major version 3.0.0, from 2.0") {
+		t.Errorf("Got status %d: %s%s", status, stdout, stderr)
+	}
+}
+
+func Test_runDiff_volumes(t *testing.T) {
+	dir := t.TempDir()
+	tool := func(image string) string {
+		return `<tool id="count" name="Count" version="1.0.0">
+	<requirements>
+		<container type="docker">` + image + `
+			<Volumes>
+				<HostPath>$input</HostPath>
+				<GuestPath>/in</GuestPath>
+			</Volumes>
+		</container>
+	</requirements>
+	<command><![CDATA[wc -l /in]]></command>
+	<inputs>
+		<param name="input" type="text" optional="false"></param>
+	</inputs>
+</tool>
+`
+	}
+	old, new := path.Join(dir, "old.xml"), path.Join(dir, "new.xml")
+	if err := os.WriteFile(old, []byte(tool("alpine:3")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(new, []byte(tool("alpine:3.20")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if status := run([]string{"diff", old, new}, stdout, stderr); status != 0 ||
+		!strings.Contains(stdout.String(), "changed from alpine:3 (docker) to alpine:3.20 (docker)") ||
+		!strings.Contains(stdout.String(), "1 changes, 0 breaking: patch version 1.0.1, from 1.0.0") {
+		t.Errorf("Got status %d: %s%s", status, stdout, stderr)
+	}
+}
@@ -0,0 +1,120 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_BashQuote(t *testing.T) {
+	var tests = map[string]string{
+		"":             "''",
+		"alpine:3":     "alpine:3",
+		"a b":          "'a b'",
+		"it's":         `'it'\''s'`,
+		`$(rm -rf /)`:  `"\$(rm -rf /)"`,
+		"--n=/a/b.txt": "--n=/a/b.txt",
+	}
+	for in, expect := range tests {
+		if out := bashQuote(in); out != expect {
+			t.Errorf("%s: got %s, expected %s", in, out, expect)
+		}
+	}
+}
+
+func Test_BashMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Description: "A \"tool\" $(whoami)",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{
+				Type:    "docker",
+				Value:   "alpine:3",
+				Volumes: []tool.VolumeMapping{{HostPath: "$dir", GuestPath: "/scratch"}},
+			}},
+		},
+		Command: &tool.Command{Value: "ls $dir"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "dir", Type: "text", Help: "it's `a` dir", Optional: false},
+			{Name: "n", Type: "integer", Optional: true},
+		}},
+		Outputs: &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+	}
+	out, err := BashMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		`"A \"tool\" \$(whoami)"`,
+		`"      it's \` + "`a\\`" + ` dir"`,
+		"\t\t--dir=*)\n\t\t\tdir=\"${1#*=}\"\n",
+		"\t\t--dir)\n",
+		`"${BARYON_CONTAINER_RUNTIME}" run --rm -v "${dir}":/scratch -v "${BARYON_OUTDIR}":/outputs -w /outputs alpine:3 ls "${dir}"`,
+		`"${BARYON_CONTAINER_RUNTIME}" exec --bind "${dir}":/scratch --bind "${BARYON_OUTDIR}":/outputs --pwd /outputs docker://alpine:3 ls "${dir}"`,
+		"\t\t--output-out_txt=*)\n\t\t\tBARYON_OUTPUT_OUT_TXT=\"${1#*=}\"\n",
+		`output="${BARYON_OUTDIR}"/out.txt`,
+		`output="$(cd "$(dirname "${BARYON_OUTPUT_OUT_TXT}")" && pwd)/$(basename "${BARYON_OUTPUT_OUT_TXT}")"`,
+		"\n## dir\n# it's `a` dir\nif [[ -z \"${dir}\" ]]; then\n",
+		`printf '{\n  "outputs": [\n%s\n  ]\n}\n' "${manifest}"`,
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+	if strings.Contains(string(out), "## n\n") {
+		t.Errorf("Expected no section of n, without help, in:\n%s", out)
+	}
+}
+
+// Test_BashMarshal_optionalWord checks that the words of the optional params
+// without default are dropped when they are empty, as in the Python script.
+func Test_BashMarshal_optionalWord(t *testing.T) {
+	in := &tool.Tool{
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "run --n=$n $label $level"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Optional: true},
+			{Name: "label", Type: "text", Optional: true},
+			{Name: "level", Type: "integer", Value: "1", Optional: true},
+		}},
+	}
+	out, err := BashMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `alpine:3 run ${n:+--n="${n}"} ${label:+"${label}"} "${level}"`
+	if !strings.Contains(string(out), expect) {
+		t.Errorf("Expected %q in:\n%s", expect, out)
+	}
+}
+
+func Test_ReservedOptions(t *testing.T) {
+	in := &tool.Tool{
+		Id: "my-tool",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "ls $outdir > out.txt"},
+		Inputs:  &tool.Inputs{Param: []tool.Param{{Name: "outdir", Type: "text"}}},
+		Outputs: &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+	}
+	if _, err := (BashMarshaler{}).Marshal(in); err == nil || !strings.Contains(err.Error(), "--outdir") {
+		t.Errorf("Expected the bash marshaler to reject outdir, got %v", err)
+	}
+	if _, err := (PythonMarshaler{}).Marshal(in); err == nil || !strings.Contains(err.Error(), "--outdir") {
+		t.Errorf("Expected the python marshaler to reject outdir, got %v", err)
+	}
+	if _, err := (PythonPackageMarshaler{}).MarshalFiles(in); err == nil || !strings.Contains(err.Error(), "outdir argument") {
+		t.Errorf("Expected the python package marshaler to reject outdir, got %v", err)
+	}
+
+	// Without outputs, the scripts have no --outdir option.
+	in.Outputs = nil
+	if _, err := (BashMarshaler{}).Marshal(in); err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+	if _, err := (PythonMarshaler{}).Marshal(in); err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"fmt"
+	"strings"
+)
+
+// cheetahCommand translates the Baryon command of t into a Galaxy Cheetah
+// template.
+//
+//   - Parameter references are single-quoted, '$param'.
+//   - A word made of a single parameter with an Argument is emitted as
+//     --argument '$param'. Parameters with an Argument that are not
+//     referenced are appended to the command.
+//   - Boolean parameters are emitted unquoted, and rendered by Galaxy as
+//     their truevalue or falsevalue (see cheetahBoolean).
+//   - Words referencing optional parameters, or boolean parameters with an
+//     Argument, are guarded by #if $param:, with the flag before them, as
+//     --label in --label $label, so that the flag is not left dangling.
+func cheetahCommand(t *tool.Tool) (string, error) {
+	if t.Command == nil {
+		return "", fmt.Errorf("[cheetahCommand]: command not specified.")
+	}
+	params := paramsByName(t.Inputs)
+	words, err := splitCommand(t.Command.Value, params)
+	if err != nil {
+		return "", fmt.Errorf("[cheetahCommand]: %v", err)
+	}
+
+	referenced := map[string]struct{}{}
+	for _, word := range words {
+		for _, name := range word.Params() {
+			referenced[name] = struct{}{}
+		}
+	}
+	if t.Inputs != nil {
+		for _, param := range t.Inputs.Param {
+			if _, ok := referenced[param.Name]; !ok && param.Argument != "" {
+				words = append(words, CommandWord{{Param: param.Name}})
+			}
+		}
+	}
+
+	lines := []string{}
+	for i := 0; i < len(words); i++ {
+		text, conditions := cheetahWord(words[i], params)
+		if isFlag(words[i]) && i+1 < len(words) {
+			if next, nextConditions := cheetahWord(words[i+1], params); len(nextConditions) > 0 {
+				text, conditions = text+" "+next, nextConditions
+				i++
+			}
+		}
+		lines = append(lines, cheetahGuard(text, conditions)...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// isFlag reports whether word is a literal option, as -n or --label.
+func isFlag(word CommandWord) bool {
+	if len(word) != 1 || word[0].Param != "" {
+		return false
+	}
+	literal := word[0].Literal
+	return len(literal) > 1 && literal[0] == '-' && (literal[1] < '0' || literal[1] > '9')
+}
+
+// cheetahWord returns the Cheetah template of word, and the conditions
+// guarding it.
+func cheetahWord(word CommandWord, params map[string]tool.Param) (string, []string) {
+	buffer := ""
+	if word.IsParam() {
+		param := params[word[0].Param]
+		if param.Type == "boolean" {
+			buffer = "$" + param.Name
+		} else {
+			buffer = fmt.Sprintf("'$%s'", param.Name)
+			if param.Argument != "" {
+				buffer = cheetahLiteral(param.Argument) + " " + buffer
+			}
+		}
+	} else {
+		for i, segment := range word {
+			if segment.Param == "" {
+				buffer += cheetahLiteral(segment.Literal)
+				continue
+			}
+			placeholder := "$" + segment.Param
+			// Avoid the placeholder to be continued by the next literal.
+			if i+1 < len(word) && word[i+1].Literal != "" {
+				next := []rune(word[i+1].Literal)[0]
+				if next == '.' || isIdentifierRune(next, false) {
+					placeholder = "${" + segment.Param + "}"
+				}
+			}
+			if params[segment.Param].Type == "boolean" {
+				buffer += placeholder
+			} else {
+				buffer += "'" + placeholder + "'"
+			}
+		}
+		if len(word) == 0 {
+			buffer = "''"
+		}
+	}
+
+	conditions := []string{}
+	for _, name := range word.Params() {
+		if condition := cheetahCondition(params[name]); condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	return buffer, conditions
+}
+
+// cheetahGuard returns the lines of the Cheetah template text, guarded by
+// the conditions.
+func cheetahGuard(text string, conditions []string) []string {
+	if len(conditions) == 0 {
+		return []string{text}
+	}
+	return []string{
+		fmt.Sprintf("#if %s:", strings.Join(conditions, " and ")),
+		"    " + text,
+		"#end if",
+	}
+}
+
+// cheetahCondition returns the condition guarding the use of an optional
+// param, or an empty string when param is required. Booleans always render
+// a value, they are guarded only when they have an Argument, so that nothing
+// is rendered when unchecked.
+func cheetahCondition(param tool.Param) string {
+	if param.Type == "boolean" && param.Argument != "" {
+		return "$" + param.Name
+	}
+	if !param.Optional {
+		return ""
+	}
+	switch param.Type {
+	case "boolean":
+		return ""
+	case "integer", "float":
+		return fmt.Sprintf("$%s is not None", param.Name)
+	default:
+		return "$" + param.Name
+	}
+}
+
+// cheetahLiteral quotes text for the shell and escapes the characters that
+// Cheetah interprets.
+func cheetahLiteral(text string) string {
+	quoted := bashQuote(text)
+	if strings.HasPrefix(quoted, `"`) {
+		quoted = "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+	}
+	return strings.NewReplacer("$", `\$`, "#", `\#`).Replace(quoted)
+}
+
+// cheetahBoolean sets the truevalue and falsevalue of a boolean param, so
+// that Galaxy does not render Python's True and False. A boolean with an
+// Argument renders as the argument when checked; it is not rendered
+// otherwise (see cheetahCondition).
+func cheetahBoolean(param *tool.Param) {
+	if param.Type != "boolean" {
+		return
+	}
+	if param.Argument != "" {
+		if param.TrueValue == "" {
+			param.TrueValue = param.Argument
+		}
+		return
+	}
+	if param.TrueValue == "" {
+		param.TrueValue = "true"
+	}
+	if param.FalseValue == "" {
+		param.FalseValue = "false"
+	}
+}
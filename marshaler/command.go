@@ -0,0 +1,152 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"fmt"
+	"strings"
+)
+
+// CommandSegment is a piece of a command word: either literal text or a
+// reference to a tool.Param by name.
+type CommandSegment struct {
+	Literal string
+	Param   string
+}
+
+// CommandWord is a single shell word of a tool.Command, made of literal text
+// and parameter references.
+type CommandWord []CommandSegment
+
+// IsParam reports whether the word is made of a single parameter reference.
+func (w CommandWord) IsParam() bool {
+	return len(w) == 1 && w[0].Param != ""
+}
+
+// Params returns the word parameter names, in order of appearance.
+func (w CommandWord) Params() []string {
+	names := []string{}
+	for _, segment := range w {
+		if segment.Param != "" {
+			names = append(names, segment.Param)
+		}
+	}
+	return names
+}
+
+// paramsByName returns the parameters declared in inputs indexed by name.
+func paramsByName(inputs *tool.Inputs) map[string]tool.Param {
+	params := map[string]tool.Param{}
+	if inputs == nil {
+		return params
+	}
+	for _, param := range inputs.Param {
+		params[param.Name] = param
+	}
+	return params
+}
+
+// splitCommand splits a command line into words following a subset of the
+// POSIX shell rules: words are separated by unquoted whitespace, single
+// quotes preserve everything literally, double quotes and backslashes escape
+// whitespace. $name and ${name} are recognized as parameter references
+// outside single quotes when name is one of params; any other variable is
+// kept literally.
+func splitCommand(command string, params map[string]tool.Param) ([]CommandWord, error) {
+	var (
+		words   []CommandWord
+		word    CommandWord
+		literal strings.Builder
+		inWord  bool
+		quote   rune
+	)
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			word = append(word, CommandSegment{Literal: literal.String()})
+			literal.Reset()
+		}
+	}
+	flushWord := func() {
+		flushLiteral()
+		if inWord {
+			words = append(words, word)
+		}
+		word = nil
+		inWord = false
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			literal.WriteRune(r)
+		case r == '\\' && i+1 < len(runes):
+			i++
+			literal.WriteRune(runes[i])
+			inWord = true
+		case r == '$':
+			name, length := parseVariable(runes[i+1:])
+			if _, ok := params[name]; !ok || name == "" {
+				literal.WriteRune(r)
+				inWord = true
+				continue
+			}
+			flushLiteral()
+			word = append(word, CommandSegment{Param: name})
+			inWord = true
+			i += length
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			literal.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			flushWord()
+		default:
+			literal.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("[splitCommand]: unterminated %c quote", quote)
+	}
+	flushWord()
+	return words, nil
+}
+
+// parseVariable reads a variable name following a "$", either in the $name
+// or ${name} form. It returns the name and the number of runes consumed.
+func parseVariable(runes []rune) (string, int) {
+	if len(runes) > 0 && runes[0] == '{' {
+		for i := 1; i < len(runes); i++ {
+			if runes[i] == '}' {
+				return string(runes[1:i]), i + 1
+			}
+		}
+		return "", 0
+	}
+	i := 0
+	for i < len(runes) && isIdentifierRune(runes[i], i == 0) {
+		i++
+	}
+	return string(runes[:i]), i
+}
+
+// isIdentifierRune reports whether r can be part of a variable name.
+func isIdentifierRune(r rune, first bool) bool {
+	switch {
+	case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return true
+	case r >= '0' && r <= '9':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"reflect"
+	"testing"
+)
+
+func Test_SplitCommand(t *testing.T) {
+	params := map[string]tool.Param{
+		"input": {Name: "input"},
+		"n":     {Name: "n"},
+	}
+	type testStruct struct {
+		Command string
+		Expect  []CommandWord
+	}
+	var tests = []testStruct{
+		{Command: "", Expect: nil},
+		{Command: "echo  $input", Expect: []CommandWord{
+			{{Literal: "echo"}},
+			{{Param: "input"}},
+		}},
+		{Command: `run --n=${n} "a b" 'c $n' $HOME`, Expect: []CommandWord{
+			{{Literal: "run"}},
+			{{Literal: "--n="}, {Param: "n"}},
+			{{Literal: "a b"}},
+			{{Literal: "c $n"}},
+			{{Literal: "$HOME"}},
+		}},
+		{Command: `a\ b ""`, Expect: []CommandWord{
+			{{Literal: "a b"}},
+			nil,
+		}},
+	}
+
+	for _, entry := range tests {
+		words, err := splitCommand(entry.Command, params)
+		if err != nil {
+			t.Errorf("%s: got error %v", entry.Command, err)
+		}
+		if !reflect.DeepEqual(words, entry.Expect) {
+			t.Errorf("%s: got %v, expected %v", entry.Command, words, entry.Expect)
+		}
+	}
+
+	if _, err := splitCommand(`echo "unterminated`, params); err == nil {
+		t.Errorf("Expected error.")
+	}
+}
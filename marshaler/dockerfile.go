@@ -0,0 +1,252 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Ensure DockerfileMarshaler implements the Marshaler and FilesMarshaler
+// interfaces at compile-time.
+var _ Marshaler = (*DockerfileMarshaler)(nil)
+var _ FilesMarshaler = (*DockerfileMarshaler)(nil)
+
+// DockerfileMarshaler marshals a tool.Tool into the Dockerfile of its
+// runtime: a rocker image installing the R packages the tool depends upon,
+// with the scripts run by its command.
+//
+// The image built from the Dockerfile is named by Image, that SetImage
+// writes back into the containers of the tool.
+type DockerfileMarshaler struct {
+	// BaseImage is the image the Dockerfile starts from,
+	// DefaultDockerBaseImage when empty.
+	BaseImage string
+	// Packages are the CRAN packages installed with install.packages.
+	Packages []string
+	// BiocPackages are the packages installed with BiocManager::install,
+	// either from Bioconductor or from CRAN.
+	BiocPackages []string
+	// Context is the directory the scripts are copied from, the current
+	// directory when empty.
+	Context string
+	// Repository of the image, DefaultDockerRepository when empty.
+	Repository string
+	// Version of the tool, tagging the image, DefaultDockerVersion when
+	// empty.
+	Version string
+}
+
+// DefaultDockerBaseImage, DefaultDockerRepository and DefaultDockerVersion
+// are the defaults of DockerfileMarshaler.
+const (
+	DefaultDockerBaseImage  = "rocker/r-ver:4.4.1"
+	DefaultDockerRepository = "baryon"
+	DefaultDockerVersion    = "0.1.0"
+)
+
+// DockerfileData is the data model of the Dockerfile template.
+type DockerfileData struct {
+	Tool         *tool.Tool
+	BaseImage    string
+	Packages     []string
+	BiocPackages []string
+	Scripts      []DockerfileScript
+}
+
+// DockerfileScript is a script copied into the image.
+type DockerfileScript struct {
+	// Source is the path of the script in the build context.
+	Source string
+	// Destination is the path of the script in the image.
+	Destination string
+}
+
+// dockerfileTemplate renders the Dockerfiles.
+var dockerfileTemplate = defaultTemplate("Dockerfile.tmpl")
+
+// rPackageRegex matches a valid R package name.
+var rPackageRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.]*$`)
+
+// Marshal implements Marshaler, returning the Dockerfile.
+func (d DockerfileMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	data, err := d.newData(t)
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.Marshal]: %v", err)
+	}
+	buffer := bytes.Buffer{}
+	if err := dockerfileTemplate.template.Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.Marshal]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// MarshalFiles implements FilesMarshaler, returning the build context: the
+// Dockerfile, the scripts, and <id>.json, the tool running Image.
+func (d DockerfileMarshaler) MarshalFiles(t *tool.Tool) ([]File, error) {
+	dockerfile, err := d.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.MarshalFiles]: %v", err)
+	}
+	files := []File{{Path: "Dockerfile", Mode: 0644, Content: dockerfile}}
+	scripts, err := d.scripts(t)
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.MarshalFiles]: %v", err)
+	}
+	for _, script := range scripts {
+		content, err := os.ReadFile(filepath.Join(d.Context, script.Source))
+		if err != nil {
+			return nil, fmt.Errorf("[DockerfileMarshaler.MarshalFiles]: %v", err)
+		}
+		files = append(files, File{Path: script.Source, Mode: 0755, Content: content})
+	}
+
+	bootstrapped := *t
+	d.SetImage(&bootstrapped)
+	content, err := JSONMarshaler{}.Marshal(&bootstrapped)
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.MarshalFiles]: %v", err)
+	}
+	return append(files, File{Path: t.Id + ".json", Mode: 0644, Content: content}), nil
+}
+
+// dockerTagRegex matches the characters a Docker tag cannot contain.
+var dockerTagRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Image returns the name of the image of t: the kebab case id of t in
+// Repository, tagged with Version, its characters invalid in a tag replaced
+// by underscores.
+func (d DockerfileMarshaler) Image(t *tool.Tool) string {
+	repository := d.Repository
+	if repository == "" {
+		repository = DefaultDockerRepository
+	}
+	version := d.Version
+	if version == "" {
+		version = DefaultDockerVersion
+	}
+	tag := strings.TrimLeft(dockerTagRegex.ReplaceAllString(version, "_"), ".-")
+	return fmt.Sprintf("%s/%s:%s", repository, strings.ReplaceAll(snakeCase(t.Id), "_", "-"), tag)
+}
+
+// SetImage writes Image into the docker containers of t, keeping their
+// volumes, or adds a docker container running it when there is none.
+func (d DockerfileMarshaler) SetImage(t *tool.Tool) {
+	image := d.Image(t)
+	requirements := tool.Requirements{}
+	if t.Requirements != nil {
+		requirements = *t.Requirements
+	}
+	containers := []tool.Container{}
+	found := false
+	for _, container := range requirements.Container {
+		if container.Type == "docker" {
+			container.Value = image
+			found = true
+		}
+		containers = append(containers, container)
+	}
+	if !found {
+		containers = append(containers, tool.Container{Type: "docker", Value: image})
+	}
+	requirements.Container = containers
+	t.Requirements = &requirements
+}
+
+// newData returns the DockerfileData of t.
+func (d DockerfileMarshaler) newData(t *tool.Tool) (*DockerfileData, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("[DockerfileMarshaler.newData]: id not specified.")
+	}
+	for _, name := range append(append([]string{}, d.Packages...), d.BiocPackages...) {
+		if !rPackageRegex.MatchString(name) {
+			return nil, fmt.Errorf("[DockerfileMarshaler.newData]: invalid R package %q.", name)
+		}
+	}
+	scripts, err := d.scripts(t)
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.newData]: %v", err)
+	}
+	data := &DockerfileData{
+		Tool:         t,
+		BaseImage:    d.BaseImage,
+		Packages:     d.Packages,
+		BiocPackages: d.BiocPackages,
+		Scripts:      scripts,
+	}
+	if data.BaseImage == "" {
+		data.BaseImage = DefaultDockerBaseImage
+	}
+	return data, nil
+}
+
+// scriptExtensions are the extensions of the scripts, lowercased.
+var scriptExtensions = []string{".r", ".sh", ".bash", ".py", ".pl", ".rb", ".jl"}
+
+// scriptsDir is the directory of the image where the scripts run by name
+// are copied, in the PATH.
+const scriptsDir = "/usr/local/bin"
+
+// commandSeparators are the words after which a command starts.
+var commandSeparators = []string{"&&", "||", ";", "|"}
+
+// scripts returns the local scripts of the command of t, copied from their
+// base name in Context: its words without parameters that are absolute
+// paths naming a file of Context, and the commands run by name, as
+// count.sh, naming a file of Context, copied into scriptsDir. The other
+// absolute paths are already in the base image, and the other words with a
+// script extension must be absolute, as the command runs in the output
+// directory.
+func (d DockerfileMarshaler) scripts(t *tool.Tool) ([]DockerfileScript, error) {
+	if t.Command == nil {
+		return nil, nil
+	}
+	words, err := splitCommand(t.Command.Value, paramsByName(t.Inputs))
+	if err != nil {
+		return nil, fmt.Errorf("[DockerfileMarshaler.scripts]: %v", err)
+	}
+	scripts := []DockerfileScript{}
+	for i, word := range words {
+		if len(word) != 1 || word[0].Param != "" {
+			continue
+		}
+		literal := word[0].Literal
+		script := slices.Contains(scriptExtensions, strings.ToLower(path.Ext(literal)))
+		local := d.isLocal(literal)
+		destination := ""
+		switch {
+		case path.IsAbs(literal) && local:
+			destination = literal
+		case path.IsAbs(literal):
+			continue
+		case !strings.Contains(literal, "/") && local && d.isCommand(words, i):
+			destination = path.Join(scriptsDir, literal)
+		case script:
+			return nil, fmt.Errorf("[DockerfileMarshaler.scripts]: script %s is not an absolute path.", literal)
+		default:
+			continue
+		}
+		scripts = append(scripts, DockerfileScript{Source: path.Base(literal), Destination: destination})
+	}
+	return scripts, nil
+}
+
+// isLocal reports whether the base name of the script is a file of Context.
+func (d DockerfileMarshaler) isLocal(script string) bool {
+	stat, err := os.Stat(filepath.Join(d.Context, path.Base(script)))
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// isCommand reports whether the word i of words starts a command.
+func (d DockerfileMarshaler) isCommand(words []CommandWord, i int) bool {
+	if i == 0 {
+		return true
+	}
+	previous := words[i-1]
+	return len(previous) == 1 && previous[0].Param == "" && slices.Contains(commandSeparators, previous[0].Literal)
+}
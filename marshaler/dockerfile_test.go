@@ -0,0 +1,105 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DockerfileMarshal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.R"), []byte("print(1)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := &tool.Tool{
+		Id: "my_tool",
+		Requirements: &tool.Requirements{Container: []tool.Container{{
+			Type:    "docker",
+			Value:   "old:1",
+			Volumes: []tool.VolumeMapping{{HostPath: "/data", GuestPath: "/data"}},
+		}}},
+		Command: &tool.Command{Value: "Rscript /scripts/run.R $n"},
+		Inputs:  &tool.Inputs{Param: []tool.Param{{Name: "n", Type: "integer"}}},
+	}
+	dockerfile := DockerfileMarshaler{
+		Version:      "1.2-3+lab",
+		Packages:     []string{"dplyr"},
+		BiocPackages: []string{"DESeq2", "data.table"},
+		Context:      dir,
+	}
+	out, err := dockerfile.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `# Runtime of the tool my_tool, generated by Baryon.
+FROM rocker/r-ver:4.4.1
+RUN Rscript -e 'install.packages(c("dplyr"))'
+RUN Rscript -e 'install.packages("BiocManager")' \
+ && Rscript -e 'BiocManager::install(c("DESeq2", "data.table"))'
+COPY run.R /scripts/run.R
+`
+	if string(out) != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, out)
+	}
+
+	files, err := dockerfile.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(files) != 3 || files[1].Path != "run.R" || files[2].Path != "my_tool.json" {
+		t.Fatalf("Got wrong files: %v", files)
+	}
+	if in.Requirements.Container[0].Value != "old:1" {
+		t.Errorf("Expected the tool to be left untouched.")
+	}
+
+	dockerfile.SetImage(in)
+	container := in.Requirements.Container[0]
+	if container.Value != "baryon/my-tool:1.2-3_lab" || len(container.Volumes) != 1 {
+		t.Errorf("Got wrong container: %+v", container)
+	}
+
+	for _, name := range []string{"count.sh", "qiime_full.sh"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("echo 1\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	in.Command.Value = "count.sh $n && bash /home/qiime_full.sh /data/in.txt | wc -l"
+	out, err = dockerfile.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !strings.HasSuffix(string(out), "COPY count.sh /usr/local/bin/count.sh\nCOPY qiime_full.sh /home/qiime_full.sh\n") {
+		t.Errorf("Got:\n%s", out)
+	}
+	files, err = dockerfile.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(files) != 4 || files[1].Path != "count.sh" || files[1].Mode != 0755 {
+		t.Errorf("Got wrong files: %v", files)
+	}
+
+	// Absolute paths missing from the context are in the base image.
+	in.Command.Value = "bash /opt/setup.sh && Rscript /scripts/run.R"
+	out, err = dockerfile.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !strings.HasSuffix(string(out), "\nCOPY run.R /scripts/run.R\n") || strings.Contains(string(out), "setup.sh") {
+		t.Errorf("Got:\n%s", out)
+	}
+	if files, err = dockerfile.MarshalFiles(in); err != nil || len(files) != 3 {
+		t.Errorf("Got files %v and error %v", files, err)
+	}
+
+	in.Command.Value = "Rscript run.R"
+	if _, err := dockerfile.Marshal(in); err == nil {
+		t.Errorf("Expected error for a relative script.")
+	}
+	if _, err := (DockerfileMarshaler{Packages: []string{"x'); system('id"}}).Marshal(in); err == nil {
+		t.Errorf("Expected error for an invalid package.")
+	}
+}
@@ -0,0 +1,253 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Ensure DocsMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*DocsMarshaler)(nil)
+
+// DocsMarshaler marshals a tool.Tool into its documentation page: the
+// description, the tables of the inputs and of the outputs, the containers,
+// the authors, the citations and how to run the generated scripts.
+//
+// The page is written in Markdown, or as a standalone HTML page when HTML is
+// set.
+type DocsMarshaler struct {
+	// HTML writes the pages in HTML instead of Markdown.
+	HTML bool
+}
+
+// DocsPage is the data model of a documentation page.
+type DocsPage struct {
+	// Tool is the documented tool.
+	Tool *tool.Tool
+	// Params are the parameters of Tool.Inputs, possibly empty.
+	Params []tool.Param
+	// Outputs are the data of Tool.Outputs, possibly empty.
+	Outputs []tool.Data
+	// Page is the path of the page, relative to the index.
+	Page string
+	// Usage holds the invocations of the scripts generated for the tool,
+	// empty when the tool has no command or no container.
+	Usage []DocsUsage
+}
+
+// DocsUsage is an example invocation of a script generated for a tool.
+type DocsUsage struct {
+	// Title names the flavour of the script.
+	Title string
+	// Language of Code, used to highlight it.
+	Language string
+	// Code invokes the script.
+	Code string
+}
+
+// docsTemplates render the pages and the indexes, in Markdown and in HTML.
+var (
+	docsMarkdownTemplate      = defaultTemplate("docs.md.tmpl")
+	docsHTMLTemplate          = defaultTemplate("docs.html.tmpl", "docs_common.tmpl")
+	docsIndexMarkdownTemplate = defaultTemplate("docs_index.md.tmpl")
+	docsIndexHTMLTemplate     = defaultTemplate("docs_index.html.tmpl", "docs_common.tmpl")
+)
+
+// Marshal implements Marshaler, returning the page of t.
+func (d DocsMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	page, err := d.newPage(t)
+	if err != nil {
+		return nil, fmt.Errorf("[DocsMarshaler.Marshal]: %v", err)
+	}
+	pageTemplate := docsMarkdownTemplate
+	if d.HTML {
+		pageTemplate = docsHTMLTemplate
+	}
+	return d.execute(pageTemplate, page)
+}
+
+// MarshalIndex returns the index page linking the pages of tools.
+func (d DocsMarshaler) MarshalIndex(tools []*tool.Tool) ([]byte, error) {
+	pages := []*DocsPage{}
+	for _, t := range tools {
+		page, err := d.newPage(t)
+		if err != nil {
+			return nil, fmt.Errorf("[DocsMarshaler.MarshalIndex]: %v", err)
+		}
+		pages = append(pages, page)
+	}
+	indexTemplate := docsIndexMarkdownTemplate
+	if d.HTML {
+		indexTemplate = docsIndexHTMLTemplate
+	}
+	return d.execute(indexTemplate, pages)
+}
+
+// MarshalSite returns the pages of tools, named after the tool ids, with
+// an index page when there are several tools.
+func (d DocsMarshaler) MarshalSite(tools []*tool.Tool) ([]File, error) {
+	files := []File{}
+	for _, t := range tools {
+		content, err := d.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("[DocsMarshaler.MarshalSite]: %v", err)
+		}
+		files = append(files, File{Path: d.PagePath(t), Mode: 0644, Content: content})
+	}
+	if len(tools) > 1 {
+		index, err := d.MarshalIndex(tools)
+		if err != nil {
+			return nil, fmt.Errorf("[DocsMarshaler.MarshalSite]: %v", err)
+		}
+		files = append(files, File{Path: "index" + d.extension(), Mode: 0644, Content: index})
+	}
+	return files, nil
+}
+
+// PagePath returns the path of the page of t.
+func (d DocsMarshaler) PagePath(t *tool.Tool) string {
+	return pythonIdentifier(t.Id) + d.extension()
+}
+
+// extension returns the file extension of the pages.
+func (d DocsMarshaler) extension() string {
+	if d.HTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+// execute renders data through pageTemplate.
+func (d DocsMarshaler) execute(pageTemplate *TemplateMarshaler, data any) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if err := pageTemplate.template.Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("[DocsMarshaler.execute]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// newPage returns the DocsPage of t. Unlike the scripts, a page does not
+// need a command or a container: without them, it has no usage.
+func (d DocsMarshaler) newPage(t *tool.Tool) (*DocsPage, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("[DocsMarshaler.newPage]: id not specified.")
+	}
+	page := &DocsPage{
+		Tool:    t,
+		Params:  []tool.Param{},
+		Outputs: outputsOf(t),
+		Page:    d.PagePath(t),
+		Usage:   []DocsUsage{},
+	}
+	if t.Inputs != nil {
+		page.Params = t.Inputs.Param
+	}
+	if t.Command == nil || t.Requirements == nil || len(t.Requirements.Container) == 0 {
+		return page, nil
+	}
+	usage, err := d.usage(page)
+	if err != nil {
+		return nil, fmt.Errorf("[DocsMarshaler.newPage]: %v", err)
+	}
+	page.Usage = usage
+	return page, nil
+}
+
+// usage returns the invocations of the bash script, of the Python script
+// and of the Python package of page, passing the parameters without a
+// default value.
+func (d DocsMarshaler) usage(page *DocsPage) ([]DocsUsage, error) {
+	id := pythonIdentifier(page.Tool.Id)
+	bash := []string{"bash", bashQuote(page.Tool.Id + ".sh")}
+	python := []string{"python3", bashQuote(page.Tool.Id + ".py")}
+	arguments := []string{}
+	for _, param := range page.Params {
+		if param.Value != "" || param.Optional {
+			continue
+		}
+		pythonType, err := PythonMarshaler{}.obtainType(param.Type)
+		if err != nil {
+			return nil, fmt.Errorf("[DocsMarshaler.usage]: %v", err)
+		}
+		value := exampleValue(param)
+		bash = append(bash, bashQuote(fmt.Sprintf("--%s=%s", param.Name, value)))
+		python = append(python, bashQuote("--"+param.Name), bashQuote(value))
+		arguments = append(arguments, fmt.Sprintf("%s=%s", pythonName(param.Name),
+			pythonLiteral(pythonType.TypeName, value)))
+	}
+
+	call := fmt.Sprintf("%s(%s)", id, strings.Join(arguments, ", "))
+	results := []string{}
+	for _, output := range page.Outputs {
+		results = append(results, pythonIdentifier(output.Name))
+	}
+	if len(results) > 0 {
+		call = strings.Join(results, ", ") + " = " + call
+	}
+	return []DocsUsage{
+		{Title: "Bash", Language: "sh", Code: strings.Join(bash, " ")},
+		{Title: "Python", Language: "sh", Code: strings.Join(python, " ")},
+		{Title: "Python package", Language: "python",
+			Code: fmt.Sprintf("from %s import %s\n\n%s", id, id, call)},
+	}, nil
+}
+
+// exampleValue returns a value of param to show in the documentation: its
+// default, its first option, or a placeholder of its type.
+func exampleValue(param tool.Param) string {
+	switch {
+	case param.Value != "":
+		return param.Value
+	case len(param.Options) > 0:
+		return param.Options[0].Value
+	}
+	switch param.Type {
+	case "integer":
+		return "1"
+	case "float":
+		return "1.0"
+	case "boolean":
+		return "true"
+	case "data":
+		return "path/to/" + param.Name
+	}
+	return strings.ToUpper(param.Name)
+}
+
+// markdownCell escapes s inside a cell of a Markdown table.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.Join(textLines(s), "<br>")
+}
+
+// markdownCode escapes s inside a Markdown code span of a table.
+func markdownCode(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "`", "'"), "|", `\|`)
+}
+
+// paragraphs splits the text s on its blank lines.
+func paragraphs(s string) []string {
+	result := []string{}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "\r", ""), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			result = append(result, paragraph)
+		}
+	}
+	return result
+}
+
+// authors returns the persons and the organization of the creator of t.
+func authors(t *tool.Tool) []string {
+	result := []string{}
+	if t.Creator == nil {
+		return result
+	}
+	for _, person := range t.Creator.Person {
+		result = append(result, person.Name)
+	}
+	if t.Creator.Organization != nil && t.Creator.Organization.Name != "" {
+		result = append(result, t.Creator.Organization.Name)
+	}
+	return result
+}
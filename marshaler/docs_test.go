@@ -0,0 +1,75 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_DocsMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id:          "my-tool",
+		Name:        "My tool",
+		Description: "Counts <things>.\n\nSecond paragraph.",
+		Creator:     &tool.Creator{Person: []tool.Person{{Name: "Ada"}}},
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "count $n $mode $in"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer"},
+			{Name: "mode", Type: "select", Options: []tool.Option{{Value: "a|b"}, {Value: "c"}}},
+			{Name: "in", Type: "data", Help: "the\ninput", Optional: true, Value: "x.txt"},
+		}},
+		Outputs: &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+		Citations: &tool.Citations{Citation: []tool.Citation{
+			{Type: "doi", Value: "10.1000/182"},
+		}},
+	}
+	out, err := DocsMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"# My tool\n\nCounts <things>.",
+		"- **Authors:** Ada",
+		"| `mode` | select |  | `a\\|b`, `c` | yes |  |",
+		"| `in` | data | `x.txt` |  | no | the<br>input |",
+		"| `out.txt` | txt |  |",
+		"[doi:10.1000/182](https://doi.org/10.1000/182)",
+		"bash my-tool.sh --n=1 '--mode=a|b'\n",
+		"out_txt = my_tool(n=1, mode=\"a|b\")\n",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+
+	out, err = DocsMarshaler{HTML: true}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"<p>Counts &lt;things&gt;.</p>\n<p>Second paragraph.</p>",
+		"<h2>Usage</h2>",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+
+	// Without a command, the page has no usage.
+	files, err := DocsMarshaler{}.MarshalSite([]*tool.Tool{in, {Id: "other"}})
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(files) != 3 || files[0].Path != "my_tool.md" || files[2].Path != "index.md" {
+		t.Fatalf("Unexpected files: %v", files)
+	}
+	if strings.Contains(string(files[1].Content), "## Usage") {
+		t.Errorf("Unexpected usage in:\n%s", files[1].Content)
+	}
+	if !strings.Contains(string(files[2].Content), "| [My tool](my_tool.md) | Counts <things>. |") {
+		t.Errorf("Unexpected index:\n%s", files[2].Content)
+	}
+}
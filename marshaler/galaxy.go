@@ -0,0 +1,136 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// Ensure GalaxyMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*GalaxyMarshaler)(nil)
+
+// GalaxyMarshaler marshals a tool.Tool into a Galaxy Tool XML file, with the
+// command translated into a Cheetah template.
+type GalaxyMarshaler struct {
+	// Macros, when not nil, are imported from MacrosFile and expanded in
+	// place of the elements they share.
+	Macros *Macros
+}
+
+// macroTool is a tool.Tool importing Macros: its elements are either the
+// ones of the tool or a macroExpand.
+type macroTool struct {
+	XMLName        xml.Name             `xml:"tool"`
+	Id             string               `xml:"id,attr"`
+	Name           string               `xml:"name,attr"`
+	Macros         macroImport          `xml:"macros"`
+	Description    string               `xml:"description"`
+	EdamTopics     *tool.EdamTopics     `xml:"edam_topics,omitempty"`
+	EdamOperations *tool.EdamOperations `xml:"edam_operations,omitempty"`
+	Xrefs          *tool.Xrefs          `xml:"xrefs,omitempty"`
+	Creator        any
+	Requirements   any
+	Command        *tool.Command `xml:"command"`
+	Inputs         *macroInputs  `xml:"inputs"`
+	Outputs        *tool.Outputs `xml:"outputs"`
+	Tests          *tool.Tests   `xml:"tests,omitempty"`
+	Citations      any
+}
+
+// macroImport imports the MacrosFile.
+type macroImport struct {
+	Import string `xml:"import"`
+}
+
+// macroInputs are the inputs of a macroTool.
+type macroInputs struct {
+	Param []any
+}
+
+// Marshal implements Marshaler.
+func (g GalaxyMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	galaxyTool, err := g.translate(t)
+	if err != nil {
+		return nil, fmt.Errorf("[GalaxyMarshaler.Marshal]: %v", err)
+	}
+	var marshaled any = galaxyTool
+	if g.Macros != nil {
+		marshaled = g.expand(galaxyTool)
+	}
+	out, err := xml.MarshalIndent(marshaled, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("[GalaxyMarshaler.Marshal]: %v", err)
+	}
+	return out, nil
+}
+
+// translate returns a copy of t with its command translated to Cheetah and
+// its params adapted to it. t is left untouched.
+func (g GalaxyMarshaler) translate(t *tool.Tool) (*tool.Tool, error) {
+	galaxyTool := *t
+	if t.Command != nil {
+		command, err := cheetahCommand(t)
+		if err != nil {
+			return nil, fmt.Errorf("[GalaxyMarshaler.translate]: %v", err)
+		}
+		galaxyTool.Command = &tool.Command{Value: command}
+	}
+	if t.Inputs != nil {
+		inputs := tool.Inputs{Param: append([]tool.Param{}, t.Inputs.Param...)}
+		for i := range inputs.Param {
+			cheetahBoolean(&inputs.Param[i])
+		}
+		galaxyTool.Inputs = &inputs
+	}
+	if t.Resources != nil {
+		requirements := tool.Requirements{}
+		if t.Requirements != nil {
+			requirements = *t.Requirements
+		}
+		requirements.Resource = resourceRequirements(t.Resources)
+		galaxyTool.Requirements = &requirements
+	}
+	return &galaxyTool, nil
+}
+
+// resourceRequirements returns the Galaxy resource requirements of
+// resources.
+func resourceRequirements(resources *tool.Resources) []tool.Resource {
+	requirements := []tool.Resource{}
+	if resources.Cpus > 0 {
+		requirements = append(requirements,
+			tool.Resource{Type: "cores_min", Value: strconv.Itoa(resources.Cpus)})
+	}
+	if resources.Memory > 0 {
+		requirements = append(requirements,
+			tool.Resource{Type: "ram_min", Value: strconv.Itoa(resources.Memory)})
+	}
+	return requirements
+}
+
+// expand returns galaxyTool importing g.Macros.
+func (g GalaxyMarshaler) expand(galaxyTool *tool.Tool) *macroTool {
+	expanded := &macroTool{
+		Id:             galaxyTool.Id,
+		Name:           galaxyTool.Name,
+		Macros:         macroImport{Import: MacrosFile},
+		Description:    galaxyTool.Description,
+		EdamTopics:     galaxyTool.EdamTopics,
+		EdamOperations: galaxyTool.EdamOperations,
+		Xrefs:          galaxyTool.Xrefs,
+		Creator:        g.Macros.expand(galaxyTool.Creator),
+		Requirements:   g.Macros.expand(galaxyTool.Requirements),
+		Command:        galaxyTool.Command,
+		Outputs:        galaxyTool.Outputs,
+		Tests:          galaxyTool.Tests,
+		Citations:      g.Macros.expand(galaxyTool.Citations),
+	}
+	if galaxyTool.Inputs != nil {
+		expanded.Inputs = &macroInputs{}
+		for _, param := range galaxyTool.Inputs.Param {
+			expanded.Inputs.Param = append(expanded.Inputs.Param, g.Macros.expand(param))
+		}
+	}
+	return expanded
+}
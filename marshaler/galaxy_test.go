@@ -0,0 +1,109 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_GalaxyMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id:      "t",
+		Command: &tool.Command{Value: "run.sh --n=$n $input $flag $verbose 'cost$'"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+			{Name: "input", Type: "data", Argument: "--input"},
+			{Name: "flag", Type: "boolean", Value: "false", Optional: true},
+			{Name: "verbose", Type: "boolean", Argument: "--verbose"},
+			{Name: "extra", Type: "text", Value: "x", Optional: true, Argument: "--extra"},
+		}},
+	}
+	out, err := GalaxyMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `<command><![CDATA[run.sh
+#if $n is not None:
+    --n='$n'
+#end if
+--input '$input'
+$flag
+#if $verbose:
+    $verbose
+#end if
+'cost\$'
+#if $extra:
+    --extra '$extra'
+#end if]]></command>`
+	if !strings.Contains(string(out), expect) {
+		t.Errorf("Expected %q in:\n%s", expect, out)
+	}
+	for _, expect := range []string{
+		`name="n" value="1" optional="true">`,
+		`name="flag" value="false" optional="true" truevalue="true" falsevalue="false"`,
+		`name="verbose" argument="--verbose" truevalue="--verbose"`,
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+	if in.Command.Value != "run.sh --n=$n $input $flag $verbose 'cost$'" ||
+		in.Inputs.Param[2].TrueValue != "" {
+		t.Errorf("Input tool was modified.")
+	}
+}
+
+// Test_GalaxyMarshal_paramAttributes checks that the label, the help and the
+// optional flag of the params are written as attributes, as Galaxy reads them.
+func Test_GalaxyMarshal_paramAttributes(t *testing.T) {
+	in := &tool.Tool{
+		Id:      "t",
+		Command: &tool.Command{Value: "run.sh $n"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Label: "Count", Help: "The number of runs.", Optional: true, RefreshOnChange: true},
+		}},
+	}
+	out, err := GalaxyMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `<param type="integer" name="n" label="Count" help="The number of runs." optional="true" refresh_on_change="true"></param>`
+	if !strings.Contains(string(out), expect) {
+		t.Errorf("Expected %q in:\n%s", expect, out)
+	}
+	for _, element := range []string{"<label>", "<help>", "<optional>", "<refresh_on_change>"} {
+		if strings.Contains(string(out), element) {
+			t.Errorf("Unexpected %q in:\n%s", element, out)
+		}
+	}
+}
+
+// Test_GalaxyMarshal_optionalFlag checks that the flag of an optional param
+// is guarded with it, so that nothing is rendered when the param is unset.
+func Test_GalaxyMarshal_optionalFlag(t *testing.T) {
+	in := &tool.Tool{
+		Id:      "t",
+		Command: &tool.Command{Value: `run.sh --label "$label" -n $n -1 $input`},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "label", Type: "text", Optional: true},
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+			{Name: "input", Type: "data"},
+		}},
+	}
+	out, err := GalaxyMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `<command><![CDATA[run.sh
+#if $label:
+    --label '$label'
+#end if
+#if $n is not None:
+    -n '$n'
+#end if
+-1
+'$input']]></command>`
+	if !strings.Contains(string(out), expect) {
+		t.Errorf("Expected %q in:\n%s", expect, out)
+	}
+}
@@ -0,0 +1,23 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"encoding/json"
+	"fmt"
+)
+
+// Ensure JSONMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*JSONMarshaler)(nil)
+
+// JSONMarshaler marshals a tool.Tool into its JSON representation, described
+// by tool.JSONSchema.
+type JSONMarshaler struct{}
+
+// Marshal implements Marshaler.
+func (j JSONMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	out, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("[JSONMarshaler.Marshal]: %v", err)
+	}
+	return append(out, '\n'), nil
+}
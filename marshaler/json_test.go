@@ -0,0 +1,44 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_JSONAndYAMLMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id:      "t",
+		Command: &tool.Command{Value: "echo $n"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+		}},
+	}
+
+	out, err := JSONMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	back := &tool.Tool{}
+	if err := json.Unmarshal(out, back); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !reflect.DeepEqual(in, back) {
+		t.Errorf("Expected %+v, got %+v", in, back)
+	}
+
+	out, err = YAMLMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	back = &tool.Tool{}
+	if err := yaml.Unmarshal(out, back); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !reflect.DeepEqual(in, back) {
+		t.Errorf("Expected %+v, got %+v", in, back)
+	}
+}
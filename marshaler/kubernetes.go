@@ -0,0 +1,337 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure KubernetesMarshaler implements the Marshaler interface at
+// compile-time.
+var _ Marshaler = (*KubernetesMarshaler)(nil)
+
+// KubernetesMarshaler marshals a tool.Tool into a Kubernetes batch/v1 Job
+// running its docker container once, with the parameters set to Values.
+//
+// The container volumes, and the output directory mounted as the working
+// directory, are persistent volume claims when written pvc:<claim>[/<path>],
+// and paths of the node otherwise. A param making the host path of a volume
+// is its source, and is passed to the command as the path of the volume in
+// the container. The Resources of the tool are both the
+// requests and the limits of the container.
+type KubernetesMarshaler struct {
+	// Values of the parameters, by name. The parameters without a value
+	// take their default.
+	Values map[string]string
+	// Outdir is the volume of the output directory, an emptyDir when empty.
+	Outdir string
+}
+
+// kubernetesJob and the following types are the subset of the Kubernetes
+// API written by KubernetesMarshaler.
+type kubernetesJob struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Spec       kubernetesJobSpec  `yaml:"spec"`
+}
+
+type kubernetesMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type kubernetesJobSpec struct {
+	BackoffLimit          int                   `yaml:"backoffLimit"`
+	ActiveDeadlineSeconds int                   `yaml:"activeDeadlineSeconds,omitempty"`
+	Template              kubernetesPodTemplate `yaml:"template"`
+}
+
+type kubernetesPodTemplate struct {
+	Metadata kubernetesMetadata `yaml:"metadata"`
+	Spec     kubernetesPodSpec  `yaml:"spec"`
+}
+
+type kubernetesPodSpec struct {
+	RestartPolicy string                `yaml:"restartPolicy"`
+	Containers    []kubernetesContainer `yaml:"containers"`
+	Volumes       []kubernetesVolume    `yaml:"volumes"`
+}
+
+type kubernetesContainer struct {
+	Name         string               `yaml:"name"`
+	Image        string               `yaml:"image"`
+	Command      []string             `yaml:"command"`
+	WorkingDir   string               `yaml:"workingDir"`
+	Resources    *kubernetesResources `yaml:"resources,omitempty"`
+	VolumeMounts []kubernetesMount    `yaml:"volumeMounts"`
+}
+
+type kubernetesResources struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+type kubernetesMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	SubPath   string `yaml:"subPath,omitempty"`
+}
+
+type kubernetesVolume struct {
+	Name                  string                 `yaml:"name"`
+	EmptyDir              *struct{}              `yaml:"emptyDir,omitempty"`
+	HostPath              *kubernetesHostPath    `yaml:"hostPath,omitempty"`
+	PersistentVolumeClaim *kubernetesVolumeClaim `yaml:"persistentVolumeClaim,omitempty"`
+}
+
+type kubernetesHostPath struct {
+	Path string `yaml:"path"`
+}
+
+type kubernetesVolumeClaim struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// kubernetesClaimPrefix prefixes the volumes that are persistent volume
+// claims.
+const kubernetesClaimPrefix = "pvc:"
+
+// Marshal implements Marshaler.
+func (k KubernetesMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: id not specified.")
+	}
+	if t.Command == nil {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: command not specified.")
+	}
+	image := ""
+	volumes := []tool.VolumeMapping{}
+	if t.Requirements != nil {
+		for _, container := range t.Requirements.Container {
+			if container.Type == "docker" {
+				image, volumes = container.Value, container.Volumes
+				break
+			}
+		}
+	}
+	if image == "" {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: no docker container.")
+	}
+	values, err := k.values(t)
+	if err != nil {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+	}
+	params := paramsByName(t.Inputs)
+	words, err := splitCommand(t.Command.Value, params)
+	if err != nil {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+	}
+
+	name := kubernetesName(t.Id)
+	labels := map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/managed-by": "baryon",
+	}
+	container := kubernetesContainer{
+		Name:       name,
+		Image:      image,
+		Command:    []string{},
+		WorkingDir: outputsGuestPath,
+		Resources:  kubernetesResourcesOf(t.Resources),
+	}
+	pod := kubernetesPodSpec{RestartPolicy: "Never"}
+	// args are the values of the params in the args: the mount path of the
+	// volumes made of a single param, whose value is the volume source.
+	args := maps.Clone(values)
+
+	outdir := tool.VolumeMapping{HostPath: k.Outdir, GuestPath: outputsGuestPath}
+	for i, volume := range append([]tool.VolumeMapping{outdir}, volumes...) {
+		host, err := k.expand(volume.HostPath, params, values)
+		if err != nil {
+			return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+		}
+		guest, err := k.expand(volume.GuestPath, params, values)
+		if err != nil {
+			return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+		}
+		if name, ok := volumeParam(volume.HostPath, params); ok && i > 0 {
+			args[name] = guest
+		}
+		mount := kubernetesMount{Name: fmt.Sprintf("volume-%d", i), MountPath: guest}
+		source := kubernetesVolume{Name: mount.Name}
+		switch {
+		case host == "":
+			source.EmptyDir = &struct{}{}
+		case strings.HasPrefix(host, kubernetesClaimPrefix):
+			claim, subPath, _ := strings.Cut(strings.TrimPrefix(host, kubernetesClaimPrefix), "/")
+			if claim == "" {
+				return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: invalid claim %s.", host)
+			}
+			source.PersistentVolumeClaim = &kubernetesVolumeClaim{ClaimName: claim}
+			mount.SubPath = subPath
+		case path.IsAbs(host):
+			source.HostPath = &kubernetesHostPath{Path: host}
+		default:
+			return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %s is neither a claim nor an absolute path.", host)
+		}
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+		pod.Volumes = append(pod.Volumes, source)
+	}
+	for _, word := range words {
+		// The command replaces the entrypoint of the image, as the command
+		// of the tool is complete. Kubernetes expands $(VAR) references in
+		// it, unless escaped.
+		arg := strings.ReplaceAll(substitute(word, args), "$(", "$$(")
+		container.Command = append(container.Command, arg)
+	}
+	pod.Containers = []kubernetesContainer{container}
+
+	job := kubernetesJob{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Metadata:   kubernetesMetadata{Name: name, Labels: labels},
+		Spec: kubernetesJobSpec{
+			BackoffLimit: 0,
+			Template: kubernetesPodTemplate{
+				Metadata: kubernetesMetadata{Name: name, Labels: labels},
+				Spec:     pod,
+			},
+		},
+	}
+	if t.Resources != nil {
+		job.Spec.ActiveDeadlineSeconds = t.Resources.Time * 60
+	}
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(job); err != nil {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("[KubernetesMarshaler.Marshal]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// values returns the values of the params of t: Values, or their default.
+// Values must name params, and the params without default must have one.
+func (k KubernetesMarshaler) values(t *tool.Tool) (map[string]string, error) {
+	params := paramsByName(t.Inputs)
+	for name := range k.Values {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("[KubernetesMarshaler.values]: unknown param %s.", name)
+		}
+	}
+	values := map[string]string{}
+	for name, param := range params {
+		value, ok := k.Values[name]
+		if !ok {
+			value = param.Value
+		}
+		if value == "" && !param.Optional {
+			return nil, fmt.Errorf("[KubernetesMarshaler.values]: param %s has no value.", name)
+		}
+		if ok && len(param.Options) > 0 && !slices.Contains(optionValues(param.Options), value) {
+			return nil, fmt.Errorf("[KubernetesMarshaler.values]: param %s must be one of: %s.",
+				name, strings.Join(optionValues(param.Options), ", "))
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// expand returns s with its params replaced by their values.
+func (k KubernetesMarshaler) expand(s string, params map[string]tool.Param, values map[string]string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	words, err := splitCommand(s, params)
+	if err != nil {
+		return "", fmt.Errorf("[KubernetesMarshaler.expand]: %v", err)
+	}
+	if len(words) != 1 {
+		return "", fmt.Errorf("[KubernetesMarshaler.expand]: invalid volume path %s.", s)
+	}
+	return substitute(words[0], values), nil
+}
+
+// volumeParam returns the name of the param the host path is made of,
+// reporting whether it is a single param.
+func volumeParam(host string, params map[string]tool.Param) (string, bool) {
+	words, err := splitCommand(host, params)
+	if err != nil || len(words) != 1 || !words[0].IsParam() {
+		return "", false
+	}
+	return words[0][0].Param, true
+}
+
+// substitute returns word with its params replaced by their values.
+func substitute(word CommandWord, values map[string]string) string {
+	buffer := strings.Builder{}
+	for _, segment := range word {
+		if segment.Param != "" {
+			buffer.WriteString(values[segment.Param])
+		} else {
+			buffer.WriteString(segment.Literal)
+		}
+	}
+	return buffer.String()
+}
+
+// kubernetesResourcesOf returns the requests and limits of resources, or
+// nil when there are none.
+func kubernetesResourcesOf(resources *tool.Resources) *kubernetesResources {
+	if resources == nil || (resources.Cpus == 0 && resources.Memory == 0) {
+		return nil
+	}
+	quantities := map[string]string{}
+	if resources.Cpus > 0 {
+		quantities["cpu"] = strconv.Itoa(resources.Cpus)
+	}
+	if resources.Memory > 0 {
+		quantities["memory"] = fmt.Sprintf("%dMi", resources.Memory)
+	}
+	return &kubernetesResources{Requests: quantities, Limits: maps.Clone(quantities)}
+}
+
+// kubernetesName returns id as a Kubernetes object name: at most 63
+// lowercase letters, digits and dashes.
+func kubernetesName(id string) string {
+	name := strings.ReplaceAll(snakeCase(id), "_", "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		name = "tool"
+	}
+	return name
+}
+
+// ReadValues reads the values of the params of a tool from a YAML or JSON
+// mapping of the param names to scalars.
+func ReadValues(in []byte) (map[string]string, error) {
+	document := map[string]any{}
+	if err := yaml.Unmarshal(in, &document); err != nil {
+		return nil, fmt.Errorf("[ReadValues]: %v", err)
+	}
+	values := map[string]string{}
+	for name, value := range document {
+		switch value := value.(type) {
+		case nil:
+			values[name] = ""
+		case map[string]any, []any:
+			return nil, fmt.Errorf("[ReadValues]: value of %s is not a scalar.", name)
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,94 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_KubernetesMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id: "My_Tool",
+		Requirements: &tool.Requirements{Container: []tool.Container{{
+			Type:    "docker",
+			Value:   "lab/img:1.0",
+			Volumes: []tool.VolumeMapping{{HostPath: "$data", GuestPath: "/data"}},
+		}}},
+		Command: &tool.Command{Value: "run --n=$n --mode $mode '$(id)'"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+			{Name: "mode", Type: "select", Options: []tool.Option{{Value: "a"}, {Value: "b"}}},
+			{Name: "data", Type: "text"},
+		}},
+		Resources: &tool.Resources{Cpus: 2, Memory: 512, Time: 10},
+	}
+	values, err := ReadValues([]byte("mode: b\ndata: pvc:shared/input\n"))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	out, err := KubernetesMarshaler{Values: values, Outdir: "/scratch"}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"kind: Job\nmetadata:\n  name: my-tool\n",
+		"  activeDeadlineSeconds: 600\n",
+		"          image: lab/img:1.0\n          command:\n            - run\n            - --n=1\n            - --mode\n            - b\n            - $$(id)\n",
+		"            requests:\n              cpu: \"2\"\n              memory: 512Mi\n",
+		"            - name: volume-1\n              mountPath: /data\n              subPath: input\n",
+		"        - name: volume-0\n          hostPath:\n            path: /scratch\n",
+		"        - name: volume-1\n          persistentVolumeClaim:\n            claimName: shared\n",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+	if strings.Contains(string(out), "args:") {
+		t.Errorf("Expected the command to replace the entrypoint, without args, in:\n%s", out)
+	}
+
+	for _, values := range []map[string]string{
+		{"data": "/x"},
+		{"mode": "c", "data": "/x"},
+		{"mode": "a", "data": "/x", "unknown": "1"},
+		{"mode": "a", "data": "relative"},
+	} {
+		if _, err := (KubernetesMarshaler{Values: values}).Marshal(in); err == nil {
+			t.Errorf("Expected error for %v", values)
+		}
+	}
+	if _, err := ReadValues([]byte("n: [1]")); err == nil {
+		t.Errorf("Expected error for a value that is not a scalar.")
+	}
+}
+
+// Test_KubernetesMarshal_claimInput checks that a param backed by a
+// persistent volume claim is passed to the command as its mount path.
+func Test_KubernetesMarshal_claimInput(t *testing.T) {
+	in := &tool.Tool{
+		Id: "count",
+		Requirements: &tool.Requirements{Container: []tool.Container{{
+			Type:    "docker",
+			Value:   "lab/wc:1.0",
+			Volumes: []tool.VolumeMapping{{HostPath: "$reads", GuestPath: "/reads"}},
+		}}},
+		Command: &tool.Command{Value: "count --dir $reads $reads/r1.fq"},
+		Inputs:  &tool.Inputs{Param: []tool.Param{{Name: "reads", Type: "text"}}},
+	}
+	out, err := KubernetesMarshaler{Values: map[string]string{"reads": "pvc:reads/run1"}}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"          command:\n            - count\n            - --dir\n            - /reads\n            - /reads/r1.fq\n",
+		"            - name: volume-1\n              mountPath: /reads\n              subPath: run1\n",
+		"          persistentVolumeClaim:\n            claimName: reads\n",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+	if strings.Contains(string(out), "pvc:") {
+		t.Errorf("Expected no volume spec in:\n%s", out)
+	}
+}
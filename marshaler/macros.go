@@ -0,0 +1,157 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+)
+
+// MacrosFile is the name of the macros file imported by the tools.
+const MacrosFile = "macros.xml"
+
+// Macros holds the elements shared by several tools, written in a MacrosFile
+// imported by each of them: the requirements, the creator, the citations and
+// the identical params. The container images are exposed as tokens.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-macros
+type Macros struct {
+	XMLName xml.Name     `xml:"macros"`
+	Tokens  []MacroToken `xml:"token"`
+	XML     []MacroXML   `xml:"xml"`
+}
+
+// MacroToken is a token, replaced by its value wherever it appears.
+type MacroToken struct {
+	XMLName xml.Name `xml:"token"`
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+// MacroXML is an XML macro, expanded by <expand macro="name"/>.
+type MacroXML struct {
+	XMLName xml.Name `xml:"xml"`
+	Name    string   `xml:"name,attr"`
+	// Content is the element of the macro, with its tokens.
+	Content any
+	// shared is the element replaced by the macro, without the tokens.
+	shared any
+}
+
+// macroExpand expands a MacroXML.
+type macroExpand struct {
+	XMLName xml.Name `xml:"expand"`
+	Macro   string   `xml:"macro,attr"`
+}
+
+// NewMacros returns the Macros shared by tools: the requirements, creator
+// and citations equal in all of them, and the params identical in at least
+// two of them. The tools are compared as translated by GalaxyMarshaler.
+func NewMacros(tools []*tool.Tool) (*Macros, error) {
+	translated := []*tool.Tool{}
+	for _, t := range tools {
+		galaxyTool, err := GalaxyMarshaler{}.translate(t)
+		if err != nil {
+			return nil, fmt.Errorf("[NewMacros]: %v", err)
+		}
+		translated = append(translated, galaxyTool)
+	}
+	macros := &Macros{}
+	if len(translated) < 2 {
+		return macros, nil
+	}
+
+	if requirements := translated[0].Requirements; requirements != nil &&
+		allEqual(translated, func(t *tool.Tool) any { return t.Requirements }) {
+		content := *requirements
+		content.Container = append([]tool.Container{}, requirements.Container...)
+		for i := range content.Container {
+			token := "@CONTAINER@"
+			if i > 0 {
+				token = fmt.Sprintf("@CONTAINER_%d@", i+1)
+			}
+			macros.Tokens = append(macros.Tokens, MacroToken{Name: token, Value: content.Container[i].Value})
+			content.Container[i].Value = token
+		}
+		macros.XML = append(macros.XML, MacroXML{Name: "requirements", Content: &content, shared: requirements})
+	}
+	if creator := translated[0].Creator; creator != nil &&
+		allEqual(translated, func(t *tool.Tool) any { return t.Creator }) {
+		macros.XML = append(macros.XML, MacroXML{Name: "creator", Content: creator, shared: creator})
+	}
+	if citations := translated[0].Citations; citations != nil &&
+		allEqual(translated, func(t *tool.Tool) any { return t.Citations }) {
+		macros.XML = append(macros.XML, MacroXML{Name: "citations", Content: citations, shared: citations})
+	}
+
+	counts := []int{}
+	params := []tool.Param{}
+	for _, t := range translated {
+		if t.Inputs == nil {
+			continue
+		}
+		for _, param := range t.Inputs.Param {
+			found := false
+			for i := range params {
+				if reflect.DeepEqual(params[i], param) {
+					counts[i]++
+					found = true
+					break
+				}
+			}
+			if !found {
+				params = append(params, param)
+				counts = append(counts, 1)
+			}
+		}
+	}
+	names := map[string]int{}
+	for i, param := range params {
+		if counts[i] < 2 {
+			continue
+		}
+		names[param.Name]++
+		name := "param_" + param.Name
+		if names[param.Name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, names[param.Name])
+		}
+		param := param
+		macros.XML = append(macros.XML, MacroXML{Name: name, Content: &param, shared: param})
+	}
+	return macros, nil
+}
+
+// allEqual reports whether element returns equal values for all tools.
+func allEqual(tools []*tool.Tool, element func(*tool.Tool) any) bool {
+	for _, t := range tools[1:] {
+		if !reflect.DeepEqual(element(tools[0]), element(t)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether m has no macro.
+func (m *Macros) Empty() bool {
+	return len(m.XML) == 0
+}
+
+// Marshal returns the MacrosFile of m.
+func (m *Macros) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("[Macros.Marshal]: %v", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// expand returns the expansion of the macro sharing element, or element
+// itself when there is none.
+func (m *Macros) expand(element any) any {
+	for _, macro := range m.XML {
+		if reflect.DeepEqual(macro.shared, element) {
+			return macroExpand{Macro: macro.Name}
+		}
+	}
+	return element
+}
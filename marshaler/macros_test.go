@@ -0,0 +1,67 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_Macros(t *testing.T) {
+	newTool := func(id string, n string) *tool.Tool {
+		return &tool.Tool{
+			Id:      id,
+			Creator: &tool.Creator{Person: []tool.Person{{Name: "Jane Doe"}}},
+			Requirements: &tool.Requirements{
+				Container: []tool.Container{{Type: "docker", Value: "lab/img:1.0"}},
+			},
+			Command: &tool.Command{Value: "run $flag $n"},
+			Inputs: &tool.Inputs{Param: []tool.Param{
+				{Name: "flag", Type: "boolean", Value: "false", Optional: true},
+				{Name: "n", Type: "integer", Value: n, Optional: true},
+			}},
+		}
+	}
+	tools := []*tool.Tool{newTool("a", "1"), newTool("b", "2")}
+	macros, err := NewMacros(tools)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	out, err := macros.Marshal()
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		`<token name="@CONTAINER@">lab/img:1.0</token>`,
+		`<container type="docker">@CONTAINER@</container>`,
+		`<xml name="creator">`,
+		`<xml name="param_flag">`,
+		`truevalue="true" falsevalue="false"`,
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+	if strings.Contains(string(out), "param_n") || strings.Contains(string(out), "citations") {
+		t.Errorf("Unexpected macros in:\n%s", out)
+	}
+
+	out, err = GalaxyMarshaler{Macros: macros}.Marshal(tools[0])
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"<macros>\n\t\t<import>macros.xml</import>\n\t</macros>",
+		`<expand macro="creator"></expand>`,
+		`<expand macro="requirements"></expand>`,
+		"<inputs>\n\t\t<expand macro=\"param_flag\"></expand>\n\t\t<param type=\"integer\" name=\"n\" value=\"1\" optional=\"true\">",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+
+	macros, _ = NewMacros(tools[:1])
+	if !macros.Empty() {
+		t.Errorf("Expected no macros for a single tool.")
+	}
+}
@@ -1,6 +1,12 @@
 package marshaler
 
-import "baryon/tool"
+import (
+	"archive/tar"
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"os"
+)
 
 // Marshaler defines an interface for serializing a tool.Tool instance.
 // Implementations of this interface are responsible for taking a
@@ -18,3 +24,45 @@ type Marshaler interface {
 	//    error: An error object in case of a failure during marshalling.
 	Marshal(*tool.Tool) ([]byte, error)
 }
+
+// File is a file produced by a FilesMarshaler.
+type File struct {
+	// Path of the file, relative to the output directory.
+	Path string
+	// Mode holds the permission bits of the file.
+	Mode os.FileMode
+	// Content of the file.
+	Content []byte
+}
+
+// FilesMarshaler defines an interface for serializing a tool.Tool instance
+// into several files, such as a package or a repository.
+type FilesMarshaler interface {
+	// MarshalFiles converts a tool.Tool instance into a list of files.
+	// Returns the files or an error if the marshalling process fails.
+	MarshalFiles(*tool.Tool) ([]File, error)
+}
+
+// archive serializes files into a tar archive, so that a FilesMarshaler can
+// also implement Marshaler.
+func archive(files []File) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	writer := tar.NewWriter(&buffer)
+	for _, file := range files {
+		if err := writer.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.Path,
+			Mode:     int64(file.Mode.Perm()),
+			Size:     int64(len(file.Content)),
+		}); err != nil {
+			return nil, fmt.Errorf("[archive]: %v", err)
+		}
+		if _, err := writer.Write(file.Content); err != nil {
+			return nil, fmt.Errorf("[archive]: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("[archive]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"fmt"
+	"strings"
+)
+
+// outputsGuestPath is where the output directory is mounted inside the
+// container. It is the working directory of the command, so that the
+// tool.Data files it writes land in the output directory.
+const outputsGuestPath = "/outputs"
+
+// outputsOf returns the data declared in the outputs of t.
+func outputsOf(t *tool.Tool) []tool.Data {
+	if t.Outputs == nil {
+		return nil
+	}
+	return t.Outputs.Data
+}
+
+// outputIdentifier converts the name of a tool.Data into an identifier, to
+// name the option and the variable holding its destination.
+func outputIdentifier(name string) string {
+	buffer := []rune{}
+	for _, r := range name {
+		if isIdentifierRune(r, false) {
+			buffer = append(buffer, r)
+		} else {
+			buffer = append(buffer, '_')
+		}
+	}
+	return string(buffer)
+}
+
+// outputVariable returns the bash variable holding the destination of the
+// output named name.
+func outputVariable(name string) string {
+	return "BARYON_OUTPUT_" + strings.ToUpper(outputIdentifier(name))
+}
+
+// scriptOptions returns the options the scripts of t add to the ones of its
+// params, without their -- prefix.
+func scriptOptions(t *tool.Tool) []string {
+	options := []string{}
+	if outputs := outputsOf(t); len(outputs) > 0 {
+		options = append(options, "outdir")
+		for _, output := range outputs {
+			options = append(options, "output-"+outputIdentifier(output.Name))
+		}
+		options = append(options, "manifest")
+	}
+	return append(options, "container-runtime", "help")
+}
+
+// checkScriptOptions returns an error when a param of t is named after one
+// of the scriptOptions, as the script could not tell them apart.
+func checkScriptOptions(t *tool.Tool) error {
+	if t.Inputs == nil {
+		return nil
+	}
+	options := scriptOptions(t)
+	for _, param := range t.Inputs.Param {
+		if has(options, param.Name) {
+			return fmt.Errorf("param %s collides with the --%s option of the script.", param.Name, param.Name)
+		}
+	}
+	return nil
+}
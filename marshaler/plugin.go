@@ -0,0 +1,115 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Ensure PluginMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*PluginMarshaler)(nil)
+
+// PluginPrefix prefixes the name of the plugin executables: the plugin of the
+// mode "cwl" is the executable baryon-gen-cwl.
+const PluginPrefix = "baryon-gen-"
+
+// PluginProtocolVersion is the version of the PluginRequest document. It is
+// incremented on every change that is not backward compatible.
+const PluginProtocolVersion = 1
+
+// PluginRequest is the JSON document written to the standard input of a
+// plugin.
+type PluginRequest struct {
+	// Version is the PluginProtocolVersion of the document.
+	Version int `json:"version"`
+	// Mode is the mode the plugin was invoked for.
+	Mode string `json:"mode"`
+	// Tool is the parsed tool.
+	Tool *tool.Tool `json:"tool"`
+}
+
+// PluginMarshaler marshals a tool.Tool through an external executable.
+//
+// The executable receives a PluginRequest on its standard input and writes
+// the generated bytes on its standard output. Its standard error is reserved
+// to diagnostics, forwarded to Stderr. A non-zero exit status fails the
+// marshaling.
+type PluginMarshaler struct {
+	// Mode is the mode of the plugin.
+	Mode string
+	// Path of the executable.
+	Path string
+	// Stderr receives the diagnostics of the plugin, os.Stderr when nil.
+	Stderr io.Writer
+}
+
+// LookupPlugin returns the PluginMarshaler of mode, running the executable
+// PluginPrefix+mode found on PATH. It returns an error wrapping
+// exec.ErrNotFound when there is none.
+func LookupPlugin(mode string) (*PluginMarshaler, error) {
+	path, err := exec.LookPath(PluginPrefix + mode)
+	if err != nil {
+		return nil, fmt.Errorf("[LookupPlugin]: %w", err)
+	}
+	return &PluginMarshaler{Mode: mode, Path: path}, nil
+}
+
+// Plugins returns the modes of the plugins found on PATH, sorted.
+func Plugins() []string {
+	modes := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			mode, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || mode == "" || slices.Contains(modes, mode) {
+				continue
+			}
+			if _, err := exec.LookPath(PluginPrefix + mode); err == nil {
+				modes = append(modes, mode)
+			}
+		}
+	}
+	slices.Sort(modes)
+	return modes
+}
+
+// Marshal implements Marshaler.
+func (p PluginMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	request, err := json.Marshal(PluginRequest{
+		Version: PluginProtocolVersion,
+		Mode:    p.Mode,
+		Tool:    t,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[PluginMarshaler.Marshal]: %v", err)
+	}
+
+	stdout := bytes.Buffer{}
+	cmd := exec.Command(p.Path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = p.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("[PluginMarshaler.Marshal]: %s exited with status %d",
+				p.Path, exitErr.ExitCode())
+		}
+		return nil, fmt.Errorf("[PluginMarshaler.Marshal]: %v", err)
+	}
+	return stdout.Bytes(), nil
+}
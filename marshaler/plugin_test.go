@@ -0,0 +1,77 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writePlugin writes an executable shell script named PluginPrefix+mode in
+// a directory prepended to PATH.
+func writePlugin(t *testing.T, mode string, script string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, PluginPrefix+mode)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func Test_PluginMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id:      "t",
+		Command: &tool.Command{Value: "echo $n"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+		}},
+	}
+
+	writePlugin(t, "echo", "echo diagnostic >&2\ncat\n")
+	plugin, err := LookupPlugin("echo")
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	stderr := bytes.Buffer{}
+	plugin.Stderr = &stderr
+	out, err := plugin.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if stderr.String() != "diagnostic\n" {
+		t.Errorf("Expected the diagnostics, got %q", stderr.String())
+	}
+	request := PluginRequest{}
+	if err := json.Unmarshal(out, &request); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if request.Version != PluginProtocolVersion || request.Mode != "echo" {
+		t.Errorf("Got wrong request: %s", out)
+	}
+	if request.Tool.Id != "t" || request.Tool.Inputs.Param[0].Value != "1" {
+		t.Errorf("Got wrong tool: %s", out)
+	}
+
+	writePlugin(t, "fail", "exit 3\n")
+	plugin, err = LookupPlugin("fail")
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if _, err := plugin.Marshal(in); err == nil {
+		t.Errorf("Expected error.")
+	}
+
+	plugins := Plugins()
+	if !slices.Contains(plugins, "echo") || !slices.Contains(plugins, "fail") {
+		t.Errorf("Expected the plugins in %v", plugins)
+	}
+
+	if _, err := LookupPlugin("missing"); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("Expected exec.ErrNotFound, got %v", err)
+	}
+}
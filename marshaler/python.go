@@ -3,191 +3,162 @@ package marshaler
 import (
 	"baryon/tool"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
 // Ensure PythonMarshaler implements the Marshaler interface at compile-time.
 var _ Marshaler = (*PythonMarshaler)(nil)
 
+// PythonMarshaler marshals a tool.Tool into a standalone Python 3 script,
+// parsing its inputs through argparse and running the tool container. The
+// script is rendered by the embedded templates/python.tmpl.
 type PythonMarshaler struct{}
 
 type PythonType struct {
-	typeName  string
-	typeCheck string
+	// TypeName is the Python type annotation of the value.
+	TypeName string
+	// ArgType is the callable used by argparse to convert the value.
+	ArgType string
 }
 
 // Obtain a PythonType from a typeName of a tool.Param.
-func (p PythonMarshaler) obtainType(typeName string, value string) (*PythonType, error) {
+func (p PythonMarshaler) obtainType(typeName string) (*PythonType, error) {
 	switch typeName {
-	case "text", "baseurl", "color", "file", "ftpfile", "hidden", "hidden_data":
-		return &PythonType{
-			typeName:  "str",
-			typeCheck: fmt.Sprintf(`not isinstance(%s, str)`, value),
-		}, nil
+	case "text", "baseurl", "color", "file", "ftpfile", "hidden", "hidden_data",
+		"genomebuild", "select":
+		return &PythonType{TypeName: "str", ArgType: "str"}, nil
 	case "integer":
-		return &PythonType{
-			typeName:  "int",
-			typeCheck: fmt.Sprintf(`not isinstance(%s, int)`, value),
-		}, nil
+		return &PythonType{TypeName: "int", ArgType: "int"}, nil
 	case "float":
-		return &PythonType{
-			typeName:  "float",
-			typeCheck: fmt.Sprintf(`not isinstance(%s, float)`, value),
-		}, nil
+		return &PythonType{TypeName: "float", ArgType: "float"}, nil
 	case "boolean":
-		return &PythonType{
-			typeName:  "bool",
-			typeCheck: fmt.Sprintf(`%s not in [True, False]`, value),
-		}, nil
-	case "genomebuild", "select":
-		return &PythonType{
-			typeName:  "enum",
-			typeCheck: fmt.Sprintf(`%s == ""`, value),
-		}, nil
+		return &PythonType{TypeName: "bool", ArgType: "_boolean"}, nil
 	case "data_column", "data", "data_collection", "drill_down":
-		return &PythonType{
-			typeName:  "file",
-			typeCheck: fmt.Sprintf(`not os.path.isfile(%s)`, value),
-		}, nil
+		return &PythonType{TypeName: "str", ArgType: "_existing_file"}, nil
 	default:
 		return nil, fmt.Errorf("unknown type: %s", typeName)
 	}
 }
 
+// pythonTemplate renders the Python scripts.
+var pythonTemplate = defaultTemplate("python.tmpl", "python_common.tmpl")
+
 // Marshal implements Marshaler.
-func (p PythonMarshaler) Marshal(tool *tool.Tool) ([]byte, error) {
-	buffer := []byte("")
-	if out, err := p.marshalDescription(`"""%s"""`, tool.Description); err != nil {
+func (p PythonMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	if err := checkScriptOptions(t); err != nil {
 		return nil, fmt.Errorf("[PythonMarshaler.Marshal]: %v", err)
-	} else {
-		buffer = append(buffer, out...)
 	}
-
-	if out, err := p.marshalInputs(tool.Inputs); err != nil {
+	out, err := pythonTemplate.Marshal(t)
+	if err != nil {
 		return nil, fmt.Errorf("[PythonMarshaler.Marshal]: %v", err)
-	} else {
-		buffer = append(buffer, out...)
 	}
+	return out, nil
+}
 
-	if tool.Command == nil {
-		return nil, fmt.Errorf("[PythonMarshaler.Marshal]: command not specified.")
+// marshalWord returns a Python expression evaluating to the command word,
+// reading parameters from the scope prefix.
+func (p PythonMarshaler) marshalWord(
+	word CommandWord,
+	params map[string]tool.Param,
+	scope string,
+) string {
+	if word.IsParam() {
+		return p.marshalValue(params[word[0].Param], scope)
 	}
-	if out, err := p.marshalContainerAndCommand(
-		tool.Requirements.Container,
-		*tool.Command,
-	); err != nil {
-		return nil, fmt.Errorf("[PythonMarshaler.Marshal]: %v", err)
-	} else {
-		buffer = append(buffer, out...)
+	hasParam := false
+	for _, segment := range word {
+		hasParam = hasParam || segment.Param != ""
 	}
-
-	return buffer, nil
-}
-
-func (p PythonMarshaler) marshalDescription(format string, description string) ([]byte, error) {
-	buffer := []byte{}
-	lines := strings.Split(description, "\n")
-	for _, l := range lines {
-		buffer = append(buffer, []byte(fmt.Sprintf(format, l))...)
-		buffer = append(buffer, []byte("\n")...)
+	if !hasParam {
+		literal := ""
+		for _, segment := range word {
+			literal += segment.Literal
+		}
+		return pythonString(literal)
 	}
-	return buffer, nil
-}
-
-func (p PythonMarshaler) marshalInputs(inputs *tool.Inputs) ([]byte, error) {
-	buffer := []byte("\n# Inputs\n")
-	if out, err := p.processParams(inputs.Param); err != nil {
-		return nil, fmt.Errorf("[PythonMarshaler.marshalInputs]: %v", err)
-	} else {
-		buffer = append(buffer, out...)
+	buffer := "f\""
+	for _, segment := range word {
+		if segment.Param != "" {
+			buffer += fmt.Sprintf("{%s}", p.marshalValue(params[segment.Param], scope))
+			continue
+		}
+		literal := strings.NewReplacer("{", "{{", "}", "}}").Replace(segment.Literal)
+		quoted := pythonString(literal)
+		buffer += quoted[1 : len(quoted)-1]
 	}
-	return append(buffer, []byte("\n# End Inputs\n")...), nil
+	return buffer + "\""
 }
 
-func (p PythonMarshaler) processParams(params []tool.Param) ([]byte, error) {
-	if len(params) == 0 {
-		return nil, nil
-	}
-	marshaledParam, err := p.marshalParam(&params[0])
-	if err != nil {
-		return nil, fmt.Errorf("[PythonMarshaler.processParams]: %v", err)
+// marshalValue returns a Python expression evaluating to the command line
+// representation of param, read from its pythonName. Booleans are lowercased
+// as in the bash scripts.
+func (p PythonMarshaler) marshalValue(param tool.Param, scope string) string {
+	if param.Type == "boolean" {
+		return fmt.Sprintf("('true' if %s%s else 'false')", scope, pythonName(param.Name))
 	}
-	remainingBytes, err := p.processParams(params[1:])
-	if err != nil {
-		return nil, fmt.Errorf("[PythonMarshaler.processParams]: %v", err)
-	}
-	return append(marshaledParam, remainingBytes...), nil
+	return fmt.Sprintf("str(%s%s)", scope, pythonName(param.Name))
 }
 
-func (p PythonMarshaler) marshalParam(param *tool.Param) ([]byte, error) {
-	if param == nil {
-		return nil, fmt.Errorf("[PythonMarshaler.marshalParam]: Empty field")
-	}
+// pythonKeywords are the reserved words of Python 3, which cannot be used
+// as identifiers.
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true,
+	"assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true,
+	"if": true, "import": true, "in": true, "is": true, "lambda": true,
+	"nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
 
-	pythonType, err := p.obtainType(param.Type, param.Name)
-	if err != nil {
-		return nil, fmt.Errorf("[PythonMarshaler.marshalParam]: %v", err)
+// pythonName returns the Python variable of the param name: name, followed
+// by an underscore when it is a keyword, as class_ for class.
+func pythonName(name string) string {
+	if pythonKeywords[name] {
+		return name + "_"
 	}
+	return name
+}
 
-	buffer := []byte(fmt.Sprintf("# %s\n", param.Help))
-	buffer = append(buffer, []byte(fmt.Sprintf(
-		`%s = None
-if "--%s=" in args:
-	%s = args["--%s="]
-if %s:
-	raise ValueError("%s is not of type %s")`,
-		param.Name,
-		param.Name,
-		param.Name,
-		param.Name,
-		pythonType.typeCheck,
-		param.Name,
-		pythonType.typeName,
-	))...)
-	return buffer, nil
+// pythonString returns s as a double-quoted Python string literal.
+func pythonString(s string) string {
+	// Go escape sequences produced by strconv.Quote are a subset of the ones
+	// understood by Python.
+	return strconv.Quote(s)
 }
 
-func (p PythonMarshaler) marshalContainerAndCommand(
-	containers []tool.Container,
-	command tool.Command,
-) ([]byte, error) {
-	buffer := []byte("# Command\n")
-	for _, container := range containers {
-		if container.Type != "docker" {
-			return nil, fmt.Errorf("Only docker is supported")
-		}
-		buffer = append(buffer, []byte(
-			fmt.Sprintf("subprocess.run(['docker', 'run', '--rm', %s'%s', f\"%s\"], check=True)\n",
-				p.marshalVolumes(container.Volumes),
-				container.Value,
-				p.marshalCommand(command),
-			))...)
-	}
-	return buffer, nil
+// pythonDocstring escapes s to be used inside a triple-quoted docstring.
+func pythonDocstring(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"""`, `\"\"\"`).Replace(s)
 }
 
-func (p PythonMarshaler) marshalCommand(command tool.Command) string {
-	buffer := []byte{}
-	for _, element := range strings.Split(command.Value, " ") {
-		if strings.HasPrefix(element, "$") {
-			buffer = append(buffer, []byte(fmt.Sprintf(
-				"{%s}", strings.TrimLeft(element, "$"),
-			))...)
-		} else {
-			buffer = append(buffer, []byte(element)...)
-		}
-		buffer = append(buffer, []byte(" ")...)
+// pythonBool returns b as a Python boolean literal.
+func pythonBool(b bool) string {
+	if b {
+		return "True"
 	}
-	return string(buffer)
+	return "False"
 }
 
-func (p PythonMarshaler) marshalVolumes(mappings []tool.VolumeMapping) string {
-	buffer := []byte{}
-	for _, mapping := range mappings {
-		hostPath := strings.TrimLeft(mapping.HostPath, "$")
-		buffer = append(buffer, []byte(fmt.Sprintf(
-			"f\" -v {%s}:%s\", ", hostPath, mapping.GuestPath,
-		))...)
+// pythonLiteral returns value as a Python literal of type typeName, falling
+// back to a string literal when value cannot be converted.
+func pythonLiteral(typeName string, value string) string {
+	switch typeName {
+	case "int":
+		if _, err := strconv.Atoi(value); err == nil {
+			return value
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil &&
+			!math.IsInf(f, 0) && !math.IsNaN(f) {
+			return value
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return pythonBool(b)
+		}
 	}
-	return string(buffer)
+	return pythonString(value)
 }
@@ -0,0 +1,129 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Ensure PythonPackageMarshaler implements the Marshaler and FilesMarshaler
+// interfaces at compile-time.
+var _ Marshaler = (*PythonPackageMarshaler)(nil)
+var _ FilesMarshaler = (*PythonPackageMarshaler)(nil)
+
+// PythonPackageMarshaler marshals a tool.Tool into an importable Python
+// package, exposing the tool as a typed function running its container.
+//
+// The package contains a pyproject.toml and a module named after the tool
+// id, defining a function with the same name.
+type PythonPackageMarshaler struct{}
+
+// Marshal implements Marshaler, returning the package as a tar archive.
+func (p PythonPackageMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	files, err := p.MarshalFiles(t)
+	if err != nil {
+		return nil, fmt.Errorf("[PythonPackageMarshaler.Marshal]: %v", err)
+	}
+	return archive(files)
+}
+
+// pythonPackageArguments are the arguments the function of the package adds
+// to the ones of the params.
+var pythonPackageArguments = []string{"outdir", "container_runtime"}
+
+// pyprojectTemplate and pythonPackageTemplate render the files of the
+// Python packages.
+var (
+	pyprojectTemplate     = defaultTemplate("pyproject.toml.tmpl")
+	pythonPackageTemplate = defaultTemplate("python_package.tmpl", "python_common.tmpl")
+)
+
+// MarshalFiles implements FilesMarshaler.
+func (p PythonPackageMarshaler) MarshalFiles(t *tool.Tool) ([]File, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("[PythonPackageMarshaler.MarshalFiles]: id not specified.")
+	}
+	if t.Inputs != nil {
+		for _, param := range t.Inputs.Param {
+			if has(pythonPackageArguments, pythonName(param.Name)) {
+				return nil, fmt.Errorf("[PythonPackageMarshaler.MarshalFiles]: param %s collides with the %s argument of the function.", param.Name, pythonName(param.Name))
+			}
+		}
+	}
+	project, err := pyprojectTemplate.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[PythonPackageMarshaler.MarshalFiles]: %v", err)
+	}
+	module, err := pythonPackageTemplate.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[PythonPackageMarshaler.MarshalFiles]: %v", err)
+	}
+	return []File{
+		{Path: "pyproject.toml", Mode: 0644, Content: project},
+		{Path: path.Join(pythonIdentifier(t.Id), "__init__.py"), Mode: 0644, Content: module},
+	}, nil
+}
+
+// annotation returns the type annotation of param. Parameters with options
+// are annotated with their Literal choices.
+func (p PythonPackageMarshaler) annotation(param tool.Param) (string, error) {
+	pythonType, err := PythonMarshaler{}.obtainType(param.Type)
+	if err != nil {
+		return "", fmt.Errorf("[PythonPackageMarshaler.annotation]: %v", err)
+	}
+	if pythonType.ArgType == "_existing_file" {
+		return "Union[str, os.PathLike]", nil
+	}
+	// Literal does not accept floats.
+	if len(param.Options) == 0 || pythonType.TypeName == "float" {
+		return pythonType.TypeName, nil
+	}
+	return fmt.Sprintf("Literal[%s]", p.choices(param, pythonType.TypeName)), nil
+}
+
+// choices returns the options of param as a list of Python literals.
+func (p PythonPackageMarshaler) choices(param tool.Param, typeName string) string {
+	choices := []string{}
+	for _, option := range param.Options {
+		choices = append(choices, pythonLiteral(typeName, option.Value))
+	}
+	return strings.Join(choices, ", ")
+}
+
+// pythonIdentifier converts s into a valid lowercase Python identifier.
+func pythonIdentifier(s string) string {
+	buffer := []rune{}
+	for _, r := range strings.ToLower(s) {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			buffer = append(buffer, r)
+		} else {
+			buffer = append(buffer, '_')
+		}
+	}
+	if len(buffer) == 0 || unicode.IsDigit(buffer[0]) {
+		buffer = append([]rune("tool_"), buffer...)
+	}
+	return string(buffer)
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	buffer := []rune{'"'}
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buffer = append(buffer, '\\', r)
+		case r == '\n':
+			buffer = append(buffer, []rune(`\n`)...)
+		case r == '\t':
+			buffer = append(buffer, []rune(`\t`)...)
+		case unicode.IsControl(r):
+			buffer = append(buffer, []rune(fmt.Sprintf(`\u%04X`, r))...)
+		default:
+			buffer = append(buffer, r)
+		}
+	}
+	return string(append(buffer, '"'))
+}
@@ -0,0 +1,111 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_PythonPackageMarshalFiles(t *testing.T) {
+	in := &tool.Tool{
+		Id:          "16s",
+		Description: "A tool.",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "run $mode"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "mode", Type: "select", Options: []tool.Option{
+				{Value: "a"}, {Value: "b"},
+			}},
+		}},
+		Outputs: &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+	}
+	files, err := PythonPackageMarshaler{}.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "pyproject.toml" ||
+		files[1].Path != "tool_16s/__init__.py" {
+		t.Fatalf("Got wrong files: %v", files)
+	}
+	for _, expect := range []string{
+		"def tool_16s(",
+		`mode: Literal["a", "b"],`,
+		") -> pathlib.Path:",
+		`OUTPUTS = [("out.txt", "txt")]`,
+		`return pathlib.Path(_manifest[0]["path"])`,
+	} {
+		if !strings.Contains(string(files[1].Content), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, files[1].Content)
+		}
+	}
+}
+
+// Test_PythonPackageKeywords checks that the params named after Python
+// keywords are renamed in the package, and keep their flag in the script.
+func Test_PythonPackageKeywords(t *testing.T) {
+	in := &tool.Tool{
+		Id: "t",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "run $class $from"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "class", Type: "text"},
+			{Name: "from", Type: "integer", Optional: true},
+		}},
+	}
+	files, err := PythonPackageMarshaler{}.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"    class_: str,\n",
+		"    from_: Union[int, None] = None,\n",
+		"command.append(str(class_))",
+		"if from_ is not None:\n        _command.append(str(from_))",
+	} {
+		if !strings.Contains(string(files[1].Content), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, files[1].Content)
+		}
+	}
+	script, err := PythonMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"        \"--class\",\n        dest=\"class_\",\n",
+		"command.append(str(args.class_))",
+	} {
+		if !strings.Contains(string(script), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, script)
+		}
+	}
+}
+
+func Test_PythonPackageLocals(t *testing.T) {
+	in := &tool.Tool{
+		Id: "t",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "run $command $runtime"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "command", Type: "text"},
+			{Name: "runtime", Type: "text"},
+		}},
+	}
+	files, err := PythonPackageMarshaler{}.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"    _runtime = _container_runtime(container_runtime)\n",
+		"    _command.append(str(command))\n    _command.append(str(runtime))\n",
+	} {
+		if !strings.Contains(string(files[1].Content), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, files[1].Content)
+		}
+	}
+}
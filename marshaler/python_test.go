@@ -0,0 +1,48 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_PythonMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Description: "A tool.",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "echo --n=$n $mode"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+			{Name: "mode", Type: "select", Options: []tool.Option{
+				{Value: "a"}, {Value: "b"},
+			}},
+			{Name: "level", Type: "integer", Value: "2"},
+		}},
+	}
+	out, err := PythonMarshaler{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	for _, expect := range []string{
+		"import argparse",
+		"type=int,\n        default=1,\n        required=False,",
+		"choices=[\"a\", \"b\"],\n        required=True,",
+		// A required param with a default does not need to be given, as in
+		// the bash script.
+		"type=int,\n        default=2,\n        required=False,",
+		`command.append(f"--n={str(args.n)}")`,
+		"command.append(str(args.mode))",
+		"sys.exit(main())",
+	} {
+		if !strings.Contains(string(out), expect) {
+			t.Errorf("Expected %q in:\n%s", expect, out)
+		}
+	}
+
+	in.Command = nil
+	if _, err := (PythonMarshaler{}).Marshal(in); err == nil {
+		t.Errorf("Expected error.")
+	}
+}
@@ -0,0 +1,116 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"fmt"
+	"strings"
+)
+
+// runtimeEnv is the environment variable choosing the container runtime of
+// the generated scripts.
+const runtimeEnv = "BARYON_CONTAINER_RUNTIME"
+
+// ContainerRuntime describes a family of programs able to run a
+// tool.Container with the same command line.
+type ContainerRuntime struct {
+	// Names of the executables, in order of preference.
+	Names []string
+	// Args starting the container, before the volumes.
+	Args []string
+	// VolumeFlag precedes each volume mapping.
+	VolumeFlag string
+	// WorkdirFlag precedes the working directory of the command.
+	WorkdirFlag string
+	// Types of tool.Container the runtime can run, in order of preference.
+	Types []string
+	// Transport prefixes docker images references, when the runtime does not
+	// pull them from docker by default.
+	Transport string
+}
+
+// containerRuntimes lists the supported runtimes, in order of preference.
+var containerRuntimes = []ContainerRuntime{
+	{
+		Names:       []string{"docker", "podman"},
+		Args:        []string{"run", "--rm"},
+		VolumeFlag:  "-v",
+		WorkdirFlag: "-w",
+		Types:       []string{"docker"},
+	},
+	{
+		Names:       []string{"apptainer", "singularity"},
+		Args:        []string{"exec"},
+		VolumeFlag:  "--bind",
+		WorkdirFlag: "--pwd",
+		Types:       []string{"singularity", "docker"},
+		Transport:   "docker://",
+	},
+}
+
+// Runtime is a ContainerRuntime paired with the container it runs.
+type Runtime struct {
+	ContainerRuntime
+	Container tool.Container
+	// Image is the container reference understood by the runtime.
+	Image string
+}
+
+// selectRuntimes returns the runtimes able to run one of containers, in
+// order of preference, each with the container it prefers.
+func selectRuntimes(containers []tool.Container) ([]Runtime, error) {
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("container not specified.")
+	}
+	selected := []Runtime{}
+	for _, runtime := range containerRuntimes {
+		if container, ok := runtime.selectContainer(containers); ok {
+			selected = append(selected, Runtime{
+				ContainerRuntime: runtime,
+				Container:        container,
+				Image:            runtime.image(container),
+			})
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no runtime supports the containers.")
+	}
+	return selected, nil
+}
+
+// runtimeNames returns the executables of runtimes, in order of preference.
+func runtimeNames(runtimes []Runtime) []string {
+	names := []string{}
+	for _, runtime := range runtimes {
+		names = append(names, runtime.Names...)
+	}
+	return names
+}
+
+// selectContainer returns the first container of the preferred type.
+func (r ContainerRuntime) selectContainer(containers []tool.Container) (tool.Container, bool) {
+	for _, containerType := range r.Types {
+		for _, container := range containers {
+			if container.Type == containerType {
+				return container, true
+			}
+		}
+	}
+	return tool.Container{}, false
+}
+
+// image returns the reference of container understood by the runtime.
+// Singularity images without a transport, such as a registry image name,
+// are pulled from docker.
+func (r ContainerRuntime) image(container tool.Container) string {
+	if r.Transport == "" {
+		return container.Value
+	}
+	if container.Type == "singularity" &&
+		(strings.Contains(container.Value, "://") ||
+			strings.HasSuffix(container.Value, ".sif") ||
+			strings.HasPrefix(container.Value, "/") ||
+			strings.HasPrefix(container.Value, ".")) {
+		return container.Value
+	}
+	return r.Transport + container.Value
+}
@@ -0,0 +1,37 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"testing"
+)
+
+func Test_SelectRuntimes(t *testing.T) {
+	runtimes, err := selectRuntimes([]tool.Container{
+		{Type: "singularity", Value: "repbioinfo/qiime2023:latest"},
+	})
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(runtimes) != 1 || runtimes[0].Names[0] != "apptainer" {
+		t.Fatalf("Got wrong runtimes: %v", runtimes)
+	}
+	if runtimes[0].Image != "docker://repbioinfo/qiime2023:latest" {
+		t.Errorf("Got wrong image: %s", runtimes[0].Image)
+	}
+
+	runtimes, err = selectRuntimes([]tool.Container{
+		{Type: "docker", Value: "alpine:3"},
+		{Type: "singularity", Value: "/images/alpine.sif"},
+	})
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if len(runtimes) != 2 || runtimes[0].Image != "alpine:3" ||
+		runtimes[1].Image != "/images/alpine.sif" {
+		t.Errorf("Got wrong runtimes: %v", runtimes)
+	}
+
+	if _, err := selectRuntimes(nil); err == nil {
+		t.Errorf("Expected error.")
+	}
+}
@@ -0,0 +1,166 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure ShedMarshaler implements the Marshaler and FilesMarshaler interfaces
+// at compile-time.
+var _ Marshaler = (*ShedMarshaler)(nil)
+var _ FilesMarshaler = (*ShedMarshaler)(nil)
+
+// ShedMarshaler marshals a tool.Tool into a Galaxy Tool Shed repository,
+// ready for planemo shed_upload.
+//
+// The repository contains the tool XML, a .shed.yml describing the
+// repository, the test-data referenced by the tests of the tool, a
+// README.md documenting it and, with Macros, the MacrosFile.
+type ShedMarshaler struct {
+	// TestData is the directory the test data are copied from, the current
+	// directory when empty.
+	TestData string
+	// Categories of the repository in the Tool Shed.
+	Categories []string
+	// Owner is the Tool Shed username owning the repository, left out of
+	// the .shed.yml when empty.
+	Owner string
+	// Macros, when not nil, are written in the MacrosFile of the repository
+	// and imported by the tool.
+	Macros *Macros
+}
+
+// ShedConfig is the .shed.yml of a Tool Shed repository.
+//
+// https://planemo.readthedocs.io/en/latest/publishing.html#configuring-a-shed-repository
+type ShedConfig struct {
+	Name            string   `yaml:"name"`
+	Owner           string   `yaml:"owner,omitempty"`
+	Description     string   `yaml:"description"`
+	LongDescription string   `yaml:"long_description,omitempty"`
+	Categories      []string `yaml:"categories,omitempty"`
+	Type            string   `yaml:"type"`
+}
+
+// Marshal implements Marshaler, returning the repository as a tar archive.
+func (s ShedMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	files, err := s.MarshalFiles(t)
+	if err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.Marshal]: %v", err)
+	}
+	return archive(files)
+}
+
+// MarshalFiles implements FilesMarshaler.
+func (s ShedMarshaler) MarshalFiles(t *tool.Tool) ([]File, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: id not specified.")
+	}
+	galaxyTool, err := GalaxyMarshaler{Macros: s.Macros}.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: %v", err)
+	}
+	config, err := s.config(t)
+	if err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: %v", err)
+	}
+	readme, err := DocsMarshaler{}.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: %v", err)
+	}
+	files := []File{
+		{Path: t.Id + ".xml", Mode: 0644, Content: append(galaxyTool, '\n')},
+		{Path: ".shed.yml", Mode: 0644, Content: config},
+		{Path: "README.md", Mode: 0644, Content: readme},
+	}
+	if s.Macros != nil {
+		macros, err := s.Macros.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: %v", err)
+		}
+		files = append(files, File{Path: MacrosFile, Mode: 0644, Content: macros})
+	}
+	testData, err := s.testData(t)
+	if err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.MarshalFiles]: %v", err)
+	}
+	return append(files, testData...), nil
+}
+
+// config returns the .shed.yml of t: the repository is named after the id
+// of t and owned by Owner.
+func (s ShedMarshaler) config(t *tool.Tool) ([]byte, error) {
+	lines := textLines(t.Description)
+	config := ShedConfig{
+		Name:        snakeCase(t.Id),
+		Owner:       s.Owner,
+		Description: lines[0],
+		Categories:  s.Categories,
+		Type:        "unrestricted",
+	}
+	if len(lines) > 1 {
+		config.LongDescription = strings.TrimSpace(t.Description)
+	}
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.config]: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("[ShedMarshaler.config]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// testData returns the files of TestData referenced by the tests of t: the
+// values of the data params and the expected outputs.
+func (s ShedMarshaler) testData(t *tool.Tool) ([]File, error) {
+	if t.Tests == nil {
+		return nil, nil
+	}
+	dataParams := map[string]bool{}
+	for _, param := range paramsByName(t.Inputs) {
+		dataParams[param.Name] = param.Type == "data"
+	}
+	names := []string{}
+	for _, test := range t.Tests.Test {
+		for _, param := range test.Param {
+			if dataParams[param.Name] {
+				names = append(names, param.Value)
+			}
+		}
+		for _, output := range test.Output {
+			names = append(names, output.File)
+		}
+	}
+
+	files := []File{}
+	copied := map[string]bool{}
+	for _, name := range names {
+		if copied[name] {
+			continue
+		}
+		copied[name] = true
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("[ShedMarshaler.testData]: %s is outside of the test data.", name)
+		}
+		content, err := os.ReadFile(filepath.Join(s.TestData, name))
+		if err != nil {
+			return nil, fmt.Errorf("[ShedMarshaler.testData]: %v", err)
+		}
+		files = append(files, File{
+			Path:    path.Join("test-data", filepath.ToSlash(name)),
+			Mode:    0644,
+			Content: content,
+		})
+	}
+	return files, nil
+}
@@ -0,0 +1,73 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ShedMarshalFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("in"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "expected.txt"), []byte("out"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := &tool.Tool{
+		Id:          "my-tool",
+		Description: "A tool.\nIt does things.",
+		Creator:     &tool.Creator{Person: []tool.Person{{Name: "Jane Doe"}}},
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "cat $in"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "in", Type: "data"},
+			{Name: "n", Type: "integer"},
+		}},
+		Outputs: &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+		Tests: &tool.Tests{Test: []tool.Test{{
+			Param:  []tool.TestParam{{Name: "in", Value: "in.txt"}, {Name: "n", Value: "1"}},
+			Output: []tool.TestOutput{{Name: "out.txt", File: "expected.txt"}},
+		}}},
+	}
+	files, err := ShedMarshaler{TestData: dir, Categories: []string{"Statistics"}, Owner: "lab"}.MarshalFiles(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	expect := "my-tool.xml .shed.yml README.md test-data/in.txt test-data/expected.txt"
+	if strings.Join(paths, " ") != expect {
+		t.Fatalf("Expected files %s, got %v", expect, paths)
+	}
+	config := `name: my_tool
+owner: lab
+description: A tool.
+long_description: |-
+  A tool.
+  It does things.
+categories:
+  - Statistics
+type: unrestricted
+`
+	if string(files[1].Content) != config {
+		t.Errorf("Expected:\n%s\nGot:\n%s", config, files[1].Content)
+	}
+	if !strings.Contains(string(files[0].Content), `<output name="out.txt" file="expected.txt"></output>`) {
+		t.Errorf("Expected the tests in:\n%s", files[0].Content)
+	}
+	if string(files[4].Content) != "out" {
+		t.Errorf("Got wrong test data: %s", files[4].Content)
+	}
+
+	in.Tests.Test[0].Output[0].File = "../expected.txt"
+	if _, err := (ShedMarshaler{TestData: dir}).MarshalFiles(in); err == nil {
+		t.Errorf("Expected error for test data outside of the directory.")
+	}
+}
@@ -0,0 +1,77 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Ensure SlurmMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*SlurmMarshaler)(nil)
+
+// SlurmMarshaler marshals a tool.Tool into a Slurm job script, submitted
+// with sbatch. The script is the one of BashMarshaler, running the container
+// with Apptainer, preceded by the #SBATCH directives of the job: its name,
+// its logs and the Resources of the tool.
+//
+// The parameters are passed as arguments of the script. Run outside of a
+// job, the script creates the directory of the logs and submits itself:
+//
+//	./tool.sbatch --name=value
+type SlurmMarshaler struct {
+	// OutputDir is the directory of the logs and, unless --outdir is given,
+	// of the outputs. The current directory when empty.
+	OutputDir string
+}
+
+// SlurmData is the data model of the header of the Slurm job scripts.
+type SlurmData struct {
+	*TemplateData
+	// OutputDir is the directory of the logs and of the outputs.
+	OutputDir string
+}
+
+// LogPath returns the path of the log of the job written with the
+// extension, such as out or err.
+func (d SlurmData) LogPath(extension string) string {
+	return path.Join(d.OutputDir, fmt.Sprintf("%s-%%j.%s", d.Tool.Id, extension))
+}
+
+// slurmTemplate renders the header of the Slurm job scripts.
+var slurmTemplate = defaultTemplate("slurm.tmpl")
+
+// Marshal implements Marshaler.
+func (s SlurmMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	data, err := NewTemplateData(t)
+	if err != nil {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: %v", err)
+	}
+	if t.Id == "" {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: id not specified.")
+	}
+	if !has(data.RuntimeNames, "apptainer") {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: no container can run with apptainer.")
+	}
+	slurmData := SlurmData{TemplateData: data, OutputDir: s.OutputDir}
+	if slurmData.OutputDir == "" {
+		slurmData.OutputDir = "."
+	}
+	if strings.ContainsAny(slurmData.OutputDir, "\"\n") {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: %q cannot be quoted in a directive.", slurmData.OutputDir)
+	}
+	buffer := bytes.Buffer{}
+	if err := slurmTemplate.template.Execute(&buffer, slurmData); err != nil {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: %v", err)
+	}
+	script, err := BashMarshaler{}.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("[SlurmMarshaler.Marshal]: %v", err)
+	}
+	// The directives must precede the commands: the shebang of the bash
+	// script is replaced by the header.
+	_, body, _ := bytes.Cut(script, []byte("\n"))
+	buffer.Write(body)
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,63 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_SlurmMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id:          "my-tool",
+		Description: "Counts.",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command:   &tool.Command{Value: "echo $n > out.txt"},
+		Inputs:    &tool.Inputs{Param: []tool.Param{{Name: "n", Type: "integer"}}},
+		Outputs:   &tool.Outputs{Data: []tool.Data{{Name: "out.txt", Format: "txt"}}},
+		Resources: &tool.Resources{Cpus: 2, Time: 30},
+	}
+	out, err := SlurmMarshaler{OutputDir: "my logs"}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	header := `#!/bin/bash
+#SBATCH --job-name=my-tool
+#SBATCH --output="my logs/my-tool-%j.out"
+#SBATCH --error="my logs/my-tool-%j.err"
+#SBATCH --cpus-per-task=2
+#SBATCH --time=30
+
+# Run outside of a job, creates the log directory, that Slurm does not
+# create, and submits the job.
+if [ -z "${SLURM_JOB_ID:-}" ] && command -v sbatch > /dev/null; then
+  mkdir -p 'my logs'
+  exec sbatch "$0" "$@"
+fi
+
+# Runs the container with Apptainer, unless $BARYON_CONTAINER_RUNTIME is set.
+export BARYON_CONTAINER_RUNTIME="${BARYON_CONTAINER_RUNTIME:-apptainer}"
+# Writes the outputs next to the logs, unless --outdir is given.
+set -- '--outdir=my logs' "$@"
+
+# Counts.
+
+set -euo pipefail
+`
+	if !strings.HasPrefix(string(out), header) {
+		t.Errorf("Expected the prefix:\n%s\nGot:\n%s", header, out)
+	}
+	if strings.Count(string(out), "#!/bin/bash") != 1 {
+		t.Errorf("Expected a single shebang in:\n%s", out)
+	}
+
+	if _, err := (SlurmMarshaler{OutputDir: `a"b`}).Marshal(in); err == nil {
+		t.Errorf("Expected error for a log directory not quotable.")
+	}
+
+	in.Requirements.Container[0].Type = "conda"
+	if _, err := (SlurmMarshaler{}).Marshal(in); err == nil {
+		t.Errorf("Expected error for a container not run by apptainer.")
+	}
+}
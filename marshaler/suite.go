@@ -0,0 +1,164 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SuiteMarshaler marshals several tools into a Galaxy suite: a Tool Shed
+// repository per tool, written by ShedMarshaler in a directory named after
+// the tool id, a tool_conf.xml registering the tools in Galaxy, and a
+// .shed.yml declaring the repositories with their suite definition. The
+// elements shared by the tools are factored into the Macros of each
+// repository.
+type SuiteMarshaler struct {
+	// Name of the suite. Its repository is named suite_<Name>.
+	Name string
+	// Categories of the repositories in the Tool Shed.
+	Categories []string
+	// Owner is the Tool Shed username owning the repositories.
+	Owner string
+	// TestData maps the id of a tool to the directory its test data are
+	// copied from, the current directory when missing.
+	TestData map[string]string
+}
+
+// ToolConf is a tool_conf.xml fragment, listing the tools at the top of the
+// tool panel or in sections.
+//
+// https://docs.galaxyproject.org/en/latest/admin/tool_panel.html
+type ToolConf struct {
+	XMLName  xml.Name          `xml:"toolbox"`
+	Tools    []ToolConfTool    `xml:"tool"`
+	Sections []ToolConfSection `xml:"section"`
+}
+
+// ToolConfSection is a section of the tool panel.
+type ToolConfSection struct {
+	XMLName xml.Name       `xml:"section"`
+	Id      string         `xml:"id,attr"`
+	Name    string         `xml:"name,attr"`
+	Tools   []ToolConfTool `xml:"tool"`
+}
+
+// ToolConfTool is a tool file, relative to the tool_conf.xml.
+type ToolConfTool struct {
+	XMLName xml.Name `xml:"tool"`
+	File    string   `xml:"file,attr"`
+}
+
+// SuiteConfig is the .shed.yml of a suite, declaring several repositories
+// and the repository_suite_definition depending on all of them.
+//
+// https://planemo.readthedocs.io/en/latest/publishing.html#publishing-multiple-repositories
+type SuiteConfig struct {
+	Owner        string                     `yaml:"owner,omitempty"`
+	Categories   []string                   `yaml:"categories,omitempty"`
+	Repositories map[string]SuiteRepository `yaml:"repositories"`
+	Suite        SuiteDefinition            `yaml:"suite"`
+}
+
+// SuiteRepository is a repository of a SuiteConfig.
+type SuiteRepository struct {
+	Description string   `yaml:"description"`
+	Include     []string `yaml:"include"`
+	Type        string   `yaml:"type"`
+}
+
+// SuiteDefinition is the repository_suite_definition of a SuiteConfig.
+type SuiteDefinition struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Type        string `yaml:"type"`
+}
+
+// MarshalSuite returns the files of the suite of tools. The tools are
+// listed in the sections of their Category, or in the section named after
+// the suite when they have none.
+func (s SuiteMarshaler) MarshalSuite(tools []*tool.Tool) ([]File, error) {
+	if s.Name == "" {
+		return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: name not specified.")
+	}
+	files := []File{}
+	toolConf := ToolConf{}
+	sections := map[string]int{}
+	config := SuiteConfig{
+		Owner:        s.Owner,
+		Categories:   s.Categories,
+		Repositories: map[string]SuiteRepository{},
+		Suite: SuiteDefinition{
+			Name:        "suite_" + snakeCase(s.Name),
+			Description: fmt.Sprintf("Suite of the %s tools.", s.Name),
+			Type:        "repository_suite_definition",
+		},
+	}
+	macros, err := NewMacros(tools)
+	if err != nil {
+		return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: %v", err)
+	}
+	if macros.Empty() {
+		macros = nil
+	}
+	for _, t := range tools {
+		shed := ShedMarshaler{TestData: s.TestData[t.Id], Categories: s.Categories, Owner: s.Owner, Macros: macros}
+		repository, err := shed.MarshalFiles(t)
+		if err != nil {
+			return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: %v", err)
+		}
+		name := snakeCase(t.Id)
+		if _, ok := config.Repositories[name]; ok {
+			return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: duplicate tool %s.", t.Id)
+		}
+		include := []string{}
+		for _, file := range repository {
+			file.Path = path.Join(t.Id, file.Path)
+			files = append(files, file)
+			if path.Base(file.Path) != ".shed.yml" {
+				include = append(include, file.Path)
+			}
+		}
+		config.Repositories[name] = SuiteRepository{
+			Description: textLines(t.Description)[0],
+			Include:     include,
+			Type:        "unrestricted",
+		}
+
+		category := t.Category
+		if category == "" {
+			category = s.Name
+		}
+		if _, ok := sections[category]; !ok {
+			sections[category] = len(toolConf.Sections)
+			toolConf.Sections = append(toolConf.Sections, ToolConfSection{
+				Id:   snakeCase(category),
+				Name: category,
+			})
+		}
+		entry := ToolConfTool{File: path.Join(t.Id, t.Id+".xml")}
+		section := &toolConf.Sections[sections[category]]
+		section.Tools = append(section.Tools, entry)
+	}
+
+	conf, err := xml.MarshalIndent(toolConf, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: %v", err)
+	}
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("[SuiteMarshaler.MarshalSuite]: %v", err)
+	}
+	return append(files,
+		File{Path: "tool_conf.xml", Mode: 0644, Content: append(conf, '\n')},
+		File{Path: ".shed.yml", Mode: 0644, Content: buffer.Bytes()},
+	), nil
+}
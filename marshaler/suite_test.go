@@ -0,0 +1,59 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"strings"
+	"testing"
+)
+
+func Test_SuiteMarshalSuite(t *testing.T) {
+	newTool := func(id string, category string) *tool.Tool {
+		return &tool.Tool{
+			Id:          id,
+			Description: "Tool " + id + ".",
+			Category:    category,
+			Requirements: &tool.Requirements{
+				Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+			},
+			Command: &tool.Command{Value: "true"},
+		}
+	}
+	tools := []*tool.Tool{newTool("a", "Stats"), newTool("b", ""), newTool("c", "Stats")}
+	files, err := SuiteMarshaler{Name: "pkg"}.MarshalSuite(tools)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	byPath := map[string]string{}
+	for _, file := range files {
+		byPath[file.Path] = string(file.Content)
+	}
+	if _, ok := byPath["b/b.xml"]; !ok {
+		t.Errorf("Expected the repository of b in %v", files)
+	}
+	toolConf := `<toolbox>
+	<section id="stats" name="Stats">
+		<tool file="a/a.xml"></tool>
+		<tool file="c/c.xml"></tool>
+	</section>
+	<section id="pkg" name="pkg">
+		<tool file="b/b.xml"></tool>
+	</section>
+</toolbox>
+`
+	if byPath["tool_conf.xml"] != toolConf {
+		t.Errorf("Expected:\n%s\nGot:\n%s", toolConf, byPath["tool_conf.xml"])
+	}
+	for _, expect := range []string{
+		"  a:\n    description: Tool a.\n    include:\n      - a/a.xml\n      - a/README.md\n",
+		"suite:\n  name: suite_pkg\n",
+		"  type: repository_suite_definition\n",
+	} {
+		if !strings.Contains(byPath[".shed.yml"], expect) {
+			t.Errorf("Expected %q in:\n%s", expect, byPath[".shed.yml"])
+		}
+	}
+
+	if _, err := (SuiteMarshaler{Name: "pkg"}).MarshalSuite(append(tools, newTool("a", ""))); err == nil {
+		t.Errorf("Expected error for duplicate tools.")
+	}
+}
@@ -0,0 +1,388 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Ensure TemplateMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*TemplateMarshaler)(nil)
+
+// templates holds the default templates of the built-in marshalers.
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// TemplateMarshaler marshals a tool.Tool by rendering a text/template with
+// a TemplateData, using the functions of templateFuncs.
+type TemplateMarshaler struct {
+	template *template.Template
+	// strict requires a command and a container, as the built-in
+	// templates run the tool.
+	strict bool
+}
+
+// NewTemplateMarshaler returns a TemplateMarshaler rendering the template
+// text. name is used in error messages.
+func NewTemplateMarshaler(name string, text string) (*TemplateMarshaler, error) {
+	parsed, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("[NewTemplateMarshaler]: %v", err)
+	}
+	return &TemplateMarshaler{template: parsed}, nil
+}
+
+// NewTemplateMarshalerFromFile returns a TemplateMarshaler rendering the
+// template found at path.
+func NewTemplateMarshalerFromFile(path string) (*TemplateMarshaler, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[NewTemplateMarshalerFromFile]: %v", err)
+	}
+	return NewTemplateMarshaler(filepath.Base(path), string(text))
+}
+
+// defaultTemplate returns the TemplateMarshaler of the embedded template
+// name, that can use the blocks defined by the embedded templates shared.
+func defaultTemplate(name string, shared ...string) *TemplateMarshaler {
+	parsed := template.New(name).Funcs(templateFuncs)
+	for _, file := range append([]string{name}, shared...) {
+		text, err := templates.ReadFile("templates/" + file)
+		if err != nil {
+			panic(err)
+		}
+		if _, err := parsed.New(file).Parse(string(text)); err != nil {
+			panic(err)
+		}
+	}
+	return &TemplateMarshaler{template: parsed.Lookup(name), strict: true}
+}
+
+// Marshal implements Marshaler.
+func (m TemplateMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	newData := newTemplateData
+	if m.strict {
+		newData = NewTemplateData
+	}
+	data, err := newData(t)
+	if err != nil {
+		return nil, fmt.Errorf("[TemplateMarshaler.Marshal]: %v", err)
+	}
+	buffer := bytes.Buffer{}
+	if err := m.template.Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("[TemplateMarshaler.Marshal]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// TemplateData is the data model rendered by a TemplateMarshaler. It exposes
+// the tool with the information derived from it by Baryon.
+type TemplateData struct {
+	// Tool is the tool being marshaled.
+	Tool *tool.Tool
+	// Params are the parameters of Tool.Inputs, possibly empty.
+	Params []tool.Param
+	// ParamsByName indexes Params by their name.
+	ParamsByName map[string]tool.Param
+	// UsedParams holds the names of the params referenced by the command or
+	// by the container volumes.
+	UsedParams map[string]bool
+	// Outputs are the data of Tool.Outputs, possibly empty.
+	Outputs []tool.Data
+	// Command is Tool.Command split into shell words, empty when the tool
+	// has no command.
+	Command []CommandWord
+	// Runtimes are the container runtimes able to run one of the tool
+	// containers, in order of preference, empty when the tool has no
+	// container.
+	Runtimes []Runtime
+	// RuntimeNames are the executables of Runtimes, in order of preference.
+	RuntimeNames []string
+	// RuntimeEnv is the environment variable choosing the container runtime.
+	RuntimeEnv string
+	// OutputsGuestPath is where the output directory is mounted inside the
+	// container, as the working directory of the command.
+	OutputsGuestPath string
+}
+
+// NewTemplateData returns the TemplateData of t. It fails when t has no
+// command or no container a runtime supports.
+func NewTemplateData(t *tool.Tool) (*TemplateData, error) {
+	if t.Command == nil {
+		return nil, fmt.Errorf("[NewTemplateData]: command not specified.")
+	}
+	if t.Requirements == nil {
+		return nil, fmt.Errorf("[NewTemplateData]: container not specified.")
+	}
+	if _, err := selectRuntimes(t.Requirements.Container); err != nil {
+		return nil, fmt.Errorf("[NewTemplateData]: %v", err)
+	}
+	data, err := newTemplateData(t)
+	if err != nil {
+		return nil, fmt.Errorf("[NewTemplateData]: %v", err)
+	}
+	return data, nil
+}
+
+// newTemplateData returns the TemplateData of t, with its Command and its
+// Runtimes filled only when t has a command and containers.
+func newTemplateData(t *tool.Tool) (*TemplateData, error) {
+	data := &TemplateData{
+		Tool:             t,
+		Params:           []tool.Param{},
+		ParamsByName:     paramsByName(t.Inputs),
+		UsedParams:       map[string]bool{},
+		Outputs:          outputsOf(t),
+		Command:          []CommandWord{},
+		Runtimes:         []Runtime{},
+		RuntimeNames:     []string{},
+		RuntimeEnv:       runtimeEnv,
+		OutputsGuestPath: outputsGuestPath,
+	}
+	if t.Inputs != nil {
+		data.Params = t.Inputs.Param
+	}
+	if t.Command != nil {
+		command, err := splitCommand(t.Command.Value, data.ParamsByName)
+		if err != nil {
+			return nil, fmt.Errorf("[newTemplateData]: %v", err)
+		}
+		data.Command = command
+	}
+
+	words := append([]CommandWord{}, data.Command...)
+	if t.Requirements != nil && len(t.Requirements.Container) > 0 {
+		runtimes, err := selectRuntimes(t.Requirements.Container)
+		if err != nil {
+			return nil, fmt.Errorf("[newTemplateData]: %v", err)
+		}
+		data.Runtimes, data.RuntimeNames = runtimes, runtimeNames(runtimes)
+		for _, container := range t.Requirements.Container {
+			for _, volume := range container.Volumes {
+				word, err := data.VolumeWord(volume)
+				if err != nil {
+					return nil, fmt.Errorf("[newTemplateData]: %v", err)
+				}
+				words = append(words, word)
+			}
+		}
+	}
+	for _, word := range words {
+		for _, name := range word.Params() {
+			data.UsedParams[name] = true
+		}
+	}
+	return data, nil
+}
+
+// VolumeWord returns the host:guest mapping of volume as a CommandWord.
+func (d TemplateData) VolumeWord(volume tool.VolumeMapping) (CommandWord, error) {
+	words, err := splitCommand(volume.HostPath+":"+volume.GuestPath, d.ParamsByName)
+	if err != nil {
+		return nil, fmt.Errorf("[TemplateData.VolumeWord]: %v", err)
+	}
+	if len(words) != 1 {
+		return nil, fmt.Errorf("[TemplateData.VolumeWord]: invalid volume %s:%s",
+			volume.HostPath, volume.GuestPath)
+	}
+	return words[0], nil
+}
+
+// templateFuncs are the functions available to the templates, on top of
+// the text/template builtins.
+var templateFuncs = template.FuncMap{
+	// Lists and strings.
+	"list":      func(items ...string) []string { return items },
+	"has":       has,
+	"dict":      dict,
+	"append":    func(list []string, items ...string) []string { return append(list, items...) },
+	"join":      func(list []string, sep string) string { return strings.Join(list, sep) },
+	"split":     strings.Split,
+	"lines":     textLines,
+	"trim":      strings.TrimSpace,
+	"replace":   strings.ReplaceAll,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"repeat":    strings.Repeat,
+	"indent":    indent,
+
+	// Params.
+	"optionValues": optionValues,
+
+	// Case conversion.
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"snakeCase":  snakeCase,
+	"kebabCase":  func(s string) string { return strings.ReplaceAll(snakeCase(s), "_", "-") },
+	"camelCase":  camelCase,
+	"pascalCase": pascalCase,
+
+	// Types.
+	"bashType":   BashMarshaler{}.obtainType,
+	"pythonType": PythonMarshaler{}.obtainType,
+	"anyType":    anyType,
+
+	"pythonAnnotation": PythonPackageMarshaler{}.annotation,
+	"pythonChoices":    PythonPackageMarshaler{}.choices,
+
+	// Quoting.
+	"bashQuote":        bashQuote,
+	"bashEscape":       bashEscapeDoubleQuoted,
+	"pythonString":     pythonString,
+	"pythonLiteral":    pythonLiteral,
+	"pythonBool":       pythonBool,
+	"pythonDocstring":  pythonDocstring,
+	"pythonIdentifier": pythonIdentifier,
+	"pythonName":       pythonName,
+	"tomlString":       tomlString,
+
+	// Command words.
+	"bashWord":   BashMarshaler{}.marshalWord,
+	"pythonWord": PythonMarshaler{}.marshalWord,
+	"pythonValue": func(param tool.Param, scope string) string {
+		return PythonMarshaler{}.marshalValue(param, scope)
+	},
+
+	// Outputs.
+	"outputIdentifier": outputIdentifier,
+	"outputVariable":   outputVariable,
+
+	// Documentation.
+	"markdownCell": markdownCell,
+	"markdownCode": markdownCode,
+	"paragraphs":   paragraphs,
+	"authors":      authors,
+	"exampleValue": exampleValue,
+}
+
+// textLines returns the lines of the trimmed text s, without carriage
+// returns.
+func textLines(s string) []string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return lines
+}
+
+// indent prefixes each non-empty line of s with prefix.
+func indent(prefix string, s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// has reports whether list contains item.
+func has(list []string, item string) bool {
+	for _, element := range list {
+		if element == item {
+			return true
+		}
+	}
+	return false
+}
+
+// dict returns a map of the alternated keys and values, to pass several
+// arguments to a template.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects key and value pairs")
+	}
+	result := map[string]any{}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %v", pairs[i])
+		}
+		result[key] = pairs[i+1]
+	}
+	return result, nil
+}
+
+// optionValues returns the values of options.
+func optionValues(options []tool.Option) []string {
+	values := []string{}
+	for _, option := range options {
+		values = append(values, option.Value)
+	}
+	return values
+}
+
+// anyType reports whether one of params is of one of types.
+func anyType(params []tool.Param, types ...string) bool {
+	for _, param := range params {
+		for _, paramType := range types {
+			if param.Type == paramType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// words splits s into lowercase words, at non alphanumeric characters and
+// at case transitions, keeping acronyms together: readCSVFile is read, csv
+// and file.
+func words(s string) []string {
+	result := []string{}
+	current := []rune{}
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(current) > 0 {
+				result = append(result, string(current))
+			}
+			current = nil
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			previous := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(previous) || nextLower {
+				result = append(result, string(current))
+				current = nil
+			}
+		}
+		current = append(current, unicode.ToLower(r))
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+// snakeCase converts s to snake_case.
+func snakeCase(s string) string {
+	return strings.Join(words(s), "_")
+}
+
+// pascalCase converts s to PascalCase.
+func pascalCase(s string) string {
+	buffer := ""
+	for _, word := range words(s) {
+		runes := []rune(word)
+		buffer += string(unicode.ToUpper(runes[0])) + string(runes[1:])
+	}
+	return buffer
+}
+
+// camelCase converts s to camelCase.
+func camelCase(s string) string {
+	runes := []rune(pascalCase(s))
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(unicode.ToLower(runes[0])) + string(runes[1:])
+}
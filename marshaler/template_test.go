@@ -0,0 +1,91 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"testing"
+)
+
+func Test_TemplateMarshal(t *testing.T) {
+	in := &tool.Tool{
+		Id: "my-tool",
+		Requirements: &tool.Requirements{
+			Container: []tool.Container{{Type: "docker", Value: "alpine:3"}},
+		},
+		Command: &tool.Command{Value: "echo --n=$n 'a b'"},
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1", Optional: true},
+			{Name: "unused", Type: "text", Value: "x y", Optional: true},
+		}},
+	}
+	marshaler, err := NewTemplateMarshaler("test", `{{pascalCase .Tool.Id}}
+{{range .Params}}{{.Name}}={{bashQuote .Value}} {{(bashType .Type .Name).TypeName}} {{index $.UsedParams .Name}}
+{{end}}{{range .Command}}{{bashWord .}} {{end}}
+{{range .Runtimes}}{{join .Names "|"}}={{.Image}};{{end}}`)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	out, err := marshaler.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	expect := `MyTool
+n=1 int true
+unused='x y' string false
+echo --n="${n}" 'a b' 
+docker|podman=alpine:3;apptainer|singularity=docker://alpine:3;`
+	if string(out) != expect {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, out)
+	}
+
+	if _, err := NewTemplateMarshaler("test", "{{.Tool"); err == nil {
+		t.Errorf("Expected parse error.")
+	}
+	marshaler, _ = NewTemplateMarshaler("test", "{{bashType .Tool.Name .Tool.Name}}")
+	if _, err := marshaler.Marshal(in); err == nil {
+		t.Errorf("Expected execution error.")
+	}
+}
+
+func Test_TemplateMarshal_noCommand(t *testing.T) {
+	in := &tool.Tool{
+		Id: "my-tool",
+		Inputs: &tool.Inputs{Param: []tool.Param{
+			{Name: "n", Type: "integer", Value: "1"},
+		}},
+	}
+	marshaler, err := NewTemplateMarshaler("test", `{{.Tool.Id}}{{range .Params}} {{.Name}}{{end}} {{len .Command}} {{len .Runtimes}}`)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	out, err := marshaler.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if expect := "my-tool n 0 0"; string(out) != expect {
+		t.Errorf("Expected %q, got %q.", expect, out)
+	}
+	if _, err := (BashMarshaler{}).Marshal(in); err == nil {
+		t.Errorf("Expected the bash marshaler to require a command.")
+	}
+}
+
+func Test_CaseConversion(t *testing.T) {
+	for _, test := range []struct {
+		in, snake, camel, pascal string
+	}{
+		{"input_dir_path", "input_dir_path", "inputDirPath", "InputDirPath"},
+		{"my-tool 2", "my_tool_2", "myTool2", "MyTool2"},
+		{"readCSVFile", "read_csv_file", "readCsvFile", "ReadCsvFile"},
+		{"", "", "", ""},
+	} {
+		if got := snakeCase(test.in); got != test.snake {
+			t.Errorf("snakeCase(%q) = %q, expected %q", test.in, got, test.snake)
+		}
+		if got := camelCase(test.in); got != test.camel {
+			t.Errorf("camelCase(%q) = %q, expected %q", test.in, got, test.camel)
+		}
+		if got := pascalCase(test.in); got != test.pascal {
+			t.Errorf("pascalCase(%q) = %q, expected %q", test.in, got, test.pascal)
+		}
+	}
+}
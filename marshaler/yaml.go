@@ -0,0 +1,30 @@
+package marshaler
+
+import (
+	"baryon/tool"
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure YAMLMarshaler implements the Marshaler interface at compile-time.
+var _ Marshaler = (*YAMLMarshaler)(nil)
+
+// YAMLMarshaler marshals a tool.Tool into its YAML representation, described
+// by tool.JSONSchema.
+type YAMLMarshaler struct{}
+
+// Marshal implements Marshaler.
+func (y YAMLMarshaler) Marshal(t *tool.Tool) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(t); err != nil {
+		return nil, fmt.Errorf("[YAMLMarshaler.Marshal]: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("[YAMLMarshaler.Marshal]: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// FormatStyle configures Format.
+type FormatStyle struct {
+	// Required is the spelling of the required instruction, ! or required.
+	Required string
+	// Width is the length of the lines over which a namespace is written
+	// one instruction per line.
+	Width int
+}
+
+// DefaultFormatStyle is the canonical FormatStyle.
+var DefaultFormatStyle = FormatStyle{Required: "!", Width: 80}
+
+// instructionOrder is the canonical order of the instructions of the Baryon
+// Namespaces, by tag. The instructions of the other tags keep their order.
+var instructionOrder = map[string][]string{
+	"param":       {"!", "type", "value", "options", "argument", "lint-ignore"},
+	"description": {"id", "name", "category", "container", "volume", "command", "resources", "citation", "lint-ignore"},
+	"return":      {"data", "test", "lint-ignore"},
+}
+
+// Format returns the R source in with its Baryon Namespaces in the canonical
+// layout of style, leaving the rest of in untouched:
+//
+//   - the instructions are sorted in the order of instructionOrder;
+//   - the required instruction is spelled as style.Required;
+//   - the whitespace the parser ignores around the arguments is removed;
+//   - a namespace fitting in style.Width is written on its line, without
+//     trailing semicolon, and otherwise one instruction per line, each
+//     ending with a semicolon.
+//
+// The formatted source parses to the same tool. The instructions of a
+// namespace whose sorting would change it, as for a volume instruction
+// between two container instructions, keep their order. A source without
+// namespace is left as is, and Format fails on the other sources that do
+// not parse.
+func Format(in []byte, style FormatStyle) ([]byte, error) {
+	if style.Required != "!" && style.Required != "required" {
+		return nil, fmt.Errorf("[parser.Format]: required must be spelled ! or required, not %q", style.Required)
+	}
+	entries := []Entry{}
+	for _, entry := range Entries(in) {
+		if entry.Namespace != nil {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return in, nil
+	}
+	want, err := NewRoxygen().Parse(in)
+	if err != nil {
+		return nil, fmt.Errorf("[parser.Format]: %v", err)
+	}
+	same := func(sorted []bool) ([]byte, bool) {
+		out := formatNamespaces(in, entries, style, sorted)
+		got, err := NewRoxygen().Parse(out)
+		return out, err == nil && reflect.DeepEqual(got, want)
+	}
+	sorted := make([]bool, len(entries))
+	for i := range sorted {
+		sorted[i] = true
+	}
+	if out, ok := same(sorted); ok {
+		return out, nil
+	}
+	// Sort only the namespaces whose sorting alone keeps the tool.
+	for i := range sorted {
+		alone := make([]bool, len(entries))
+		alone[i] = true
+		_, sorted[i] = same(alone)
+	}
+	if out, ok := same(sorted); ok {
+		return out, nil
+	}
+	return nil, fmt.Errorf("[parser.Format]: the formatted source does not parse to the same tool")
+}
+
+// formatNamespaces returns in with the namespaces of entries formatted,
+// the instructions of the i-th sorted when sorted[i] is true.
+func formatNamespaces(in []byte, entries []Entry, style FormatStyle, sorted []bool) []byte {
+	out := []byte{}
+	end := 0
+	for i, entry := range entries {
+		out = append(out, in[end:entry.Namespace.Start]...)
+		out = append(out, formatNamespace(in, entry, style, sorted[i])...)
+		end = entry.Namespace.End
+	}
+	return append(out, in[end:]...)
+}
+
+// formatNamespace returns the namespace of entry in the source in,
+// formatted.
+func formatNamespace(in []byte, entry Entry, style FormatStyle, sorted bool) string {
+	namespace := entry.Namespace
+	_, column := Position(in, namespace.Start)
+	rest := in[namespace.End:]
+	if i := strings.IndexByte(string(rest), '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	instructions := sortInstructions(entry.Tag, namespace.Instructions, sorted)
+	return layoutNamespace(instructions, column-1+len(rest), style)
+}
+
+// FormatNamespace returns a Baryon Namespace of tag with the instructions,
+// formatted in style for a roxygen2 line ending with it at column,
+// starting at 1.
+func FormatNamespace(tag string, instructions []Instruction, column int, style FormatStyle) string {
+	return layoutNamespace(sortInstructions(tag, instructions, true), column-1, style)
+}
+
+// layoutNamespace returns a namespace with the instructions, on the line
+// when it fits in style.Width with the used columns of the line, and
+// otherwise one instruction per line.
+func layoutNamespace(instructions []Instruction, used int, style FormatStyle) string {
+	formatted := []string{}
+	for _, instruction := range instructions {
+		formatted = append(formatted, formatInstruction(instruction, style))
+	}
+	if len(formatted) == 0 {
+		return "$B{}"
+	}
+	line := "$B{" + strings.Join(formatted, ";") + "}"
+	if !strings.Contains(line, "\n") && used+len(line) <= style.Width {
+		return line
+	}
+	lines := []string{"$B{"}
+	for _, instruction := range formatted {
+		lines = append(lines, "    "+instruction+";")
+	}
+	lines = append(lines, "}")
+	return continueLines(strings.Join(lines, "\n"))
+}
+
+// continueLines prefixes the lines of s after the first with the roxygen2
+// prefix, as the parser strips it.
+func continueLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" {
+			lines[i] = "#'"
+		} else {
+			lines[i] = "#' " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sortInstructions returns the instructions in the order of the tag, when
+// sorted is true.
+func sortInstructions(tag string, instructions []Instruction, sorted bool) []Instruction {
+	order, ok := instructionOrder[tag]
+	if !sorted || !ok {
+		return instructions
+	}
+	rank := func(instruction Instruction) int {
+		name := instruction.Name
+		if name == "required" {
+			name = "!"
+		}
+		if i := slices.Index(order, name); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	sortedInstructions := slices.Clone(instructions)
+	slices.SortStableFunc(sortedInstructions, func(a, b Instruction) int {
+		return rank(a) - rank(b)
+	})
+	return sortedInstructions
+}
+
+// formatInstruction returns the canonical form of instruction, without the
+// whitespace its parser ignores.
+func formatInstruction(instruction Instruction, style FormatStyle) string {
+	name, args := instruction.Name, instruction.Args
+	switch name {
+	case "!", "required":
+		return style.Required
+	case "options", "test", "resources", "lint-ignore":
+		args = joinList(args, ",", true)
+	case "container":
+		args = joinList(args, ",", false)
+	case "data":
+		args = strings.TrimRight(joinList(args, ",", false), ",")
+	case "volume":
+		args = joinList(args, ":", false)
+	case "citation":
+		args = strings.TrimSpace(args)
+		if citationType, value, ok := strings.Cut(args, ","); ok {
+			args = strings.TrimSpace(citationType) + "," + strings.TrimSpace(value)
+		}
+	case "command", "argument", "category":
+		args = strings.TrimSpace(args)
+	}
+	if args == "" && name == "lint-ignore" {
+		return name
+	}
+	return name + "(" + args + ")"
+}
+
+// joinList returns the elements of the list s separated by separator, each
+// trimmed, the empty ones dropped when dropEmpty is true. The name=value
+// elements are trimmed around =.
+func joinList(s string, separator string, dropEmpty bool) string {
+	elements := []string{}
+	for _, element := range strings.Split(s, separator) {
+		element = strings.TrimSpace(element)
+		if name, value, ok := strings.Cut(element, "="); ok {
+			element = strings.TrimSpace(name) + "=" + strings.TrimSpace(value)
+		}
+		if element == "" && dropEmpty {
+			continue
+		}
+		elements = append(elements, element)
+	}
+	return strings.Join(elements, separator)
+}
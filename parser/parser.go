@@ -8,3 +8,16 @@ type Parser interface {
 	// Parse parses a []byte.
 	Parse([]byte) (*tool.Tool, error)
 }
+
+// Defaults are the values shared by the tools of a project. They are applied
+// before the instructions of each tool, which override them.
+type Defaults struct {
+	// Container is the image of the tools without container instruction.
+	Container string
+	// ContainerType is the type of Container, docker when empty.
+	ContainerType string
+	// Organization is the organization of the creator of the tools.
+	Organization string
+	// IdPrefix prefixes the ids of the tools not starting with it.
+	IdPrefix string
+}
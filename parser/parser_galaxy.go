@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"baryon/tool"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// galaxyParser loads a tool from its Galaxy tool XML, as produced by the
+// galaxy mode, expanding its macros.
+type galaxyParser struct {
+	// dir is the directory of the tool XML, where its imported macros
+	// files are read.
+	dir string
+}
+
+// NewGalaxy returns a new galaxyParser reading the imported macros files in
+// dir.
+func NewGalaxy(dir string) *galaxyParser {
+	return &galaxyParser{dir: dir}
+}
+
+// galaxyMacros are the macros of a tool XML or of a macros file.
+type galaxyMacros struct {
+	Import []string `xml:"import"`
+	Token  []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"token"`
+	XML []struct {
+		Name  string `xml:"name,attr"`
+		Inner string `xml:",innerxml"`
+	} `xml:"xml"`
+}
+
+// galaxyElements are the macros of a tool XML, and the elements of its
+// params the tool.Tool reads as attributes, as written by former versions
+// of the galaxy mode.
+type galaxyElements struct {
+	Macros *galaxyMacros `xml:"macros"`
+	Inputs struct {
+		Param []struct {
+			Label    string `xml:"label"`
+			Help     string `xml:"help"`
+			Optional string `xml:"optional"`
+		} `xml:"param"`
+	} `xml:"inputs"`
+}
+
+// expandRegex matches the expansions of the XML macros.
+var expandRegex = regexp.MustCompile(`<expand\s+macro="([^"]*)"\s*(?:/>|>\s*</expand>)`)
+
+// maxExpansions limits the depth of the macros expanding other macros.
+const maxExpansions = 10
+
+// Parse implements Parser. The label, the help and the optional flag of
+// the params are read from their attributes or from their elements.
+func (g *galaxyParser) Parse(in []byte) (*tool.Tool, error) {
+	elements := galaxyElements{}
+	if err := xml.Unmarshal(in, &elements); err != nil {
+		return nil, fmt.Errorf("[galaxyParser.Parse]: %v", err)
+	}
+	if elements.Macros != nil {
+		expanded, err := g.expand(string(in), elements.Macros)
+		if err != nil {
+			return nil, fmt.Errorf("[galaxyParser.Parse]: %v", err)
+		}
+		in = []byte(expanded)
+		elements = galaxyElements{}
+		if err := xml.Unmarshal(in, &elements); err != nil {
+			return nil, fmt.Errorf("[galaxyParser.Parse]: %v", err)
+		}
+	}
+	var outtool tool.Tool
+	if err := xml.Unmarshal(in, &outtool); err != nil {
+		return nil, fmt.Errorf("[galaxyParser.Parse]: %v", err)
+	}
+	if outtool.Inputs != nil {
+		for i, element := range elements.Inputs.Param {
+			if i >= len(outtool.Inputs.Param) {
+				break
+			}
+			param := &outtool.Inputs.Param[i]
+			if param.Label == "" {
+				param.Label = strings.TrimSpace(element.Label)
+			}
+			if param.Help == "" {
+				param.Help = strings.TrimSpace(element.Help)
+			}
+			if strings.TrimSpace(element.Optional) == "true" {
+				param.Optional = true
+			}
+		}
+	}
+	return &outtool, nil
+}
+
+// expand returns the tool XML in with its macros expanded, from the
+// macros and the macros files they import.
+func (g *galaxyParser) expand(in string, macros *galaxyMacros) (string, error) {
+	all := []*galaxyMacros{macros}
+	for _, name := range macros.Import {
+		content, err := os.ReadFile(filepath.Join(g.dir, strings.TrimSpace(name)))
+		if err != nil {
+			return "", fmt.Errorf("macros: %v", err)
+		}
+		imported := &galaxyMacros{}
+		if err := xml.Unmarshal(content, imported); err != nil {
+			return "", fmt.Errorf("macros %s: %v", name, err)
+		}
+		all = append(all, imported)
+	}
+	xmls := map[string]string{}
+	replacements := []string{}
+	for _, m := range all {
+		for _, x := range m.XML {
+			xmls[x.Name] = x.Inner
+		}
+		for _, token := range m.Token {
+			replacements = append(replacements, token.Name, token.Value)
+		}
+	}
+	for i := 0; expandRegex.MatchString(in); i++ {
+		if i == maxExpansions {
+			return "", fmt.Errorf("macros: too deep expansion")
+		}
+		var missing error
+		in = expandRegex.ReplaceAllStringFunc(in, func(expansion string) string {
+			name := expandRegex.FindStringSubmatch(expansion)[1]
+			content, ok := xmls[name]
+			if !ok {
+				missing = fmt.Errorf("macros: unknown macro %q", name)
+			}
+			return content
+		})
+		if missing != nil {
+			return "", missing
+		}
+	}
+	return strings.NewReplacer(replacements...).Replace(in), nil
+}
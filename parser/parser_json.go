@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"baryon/tool"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonParser loads a tool from its JSON representation, as produced by the
+// json mode and described by tool.JSONSchema.
+type jsonParser struct{}
+
+// NewJSON returns a new jsonParser.
+func NewJSON() *jsonParser {
+	return &jsonParser{}
+}
+
+// Parse implements Parser. Unknown fields are rejected, and the params,
+// containers and data are validated as when parsing roxygen.
+func (*jsonParser) Parse(in []byte) (*tool.Tool, error) {
+	var outtool tool.Tool
+	decoder := json.NewDecoder(bytes.NewReader(in))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&outtool); err != nil {
+		return nil, fmt.Errorf("[jsonParser.Parse]: %v", err)
+	}
+	if err := validate(&outtool); err != nil {
+		return nil, fmt.Errorf("[jsonParser.Parse]: %v", err)
+	}
+	return &outtool, nil
+}
+
+// validate validates the Validable elements of t.
+func validate(t *tool.Tool) error {
+	if t.Requirements != nil {
+		for _, container := range t.Requirements.Container {
+			if err := container.Validate(); err != nil {
+				return fmt.Errorf("container %s: %v", container.Value, err)
+			}
+		}
+	}
+	if t.Resources != nil {
+		if err := t.Resources.Validate(); err != nil {
+			return fmt.Errorf("resources: %v", err)
+		}
+	}
+	if t.Inputs != nil {
+		for _, param := range t.Inputs.Param {
+			if err := param.Validate(); err != nil {
+				return fmt.Errorf("param %s: %v", param.Name, err)
+			}
+		}
+	}
+	if t.Outputs != nil {
+		for _, data := range t.Outputs.Data {
+			if err := data.Validate(); err != nil {
+				return fmt.Errorf("data %s: %v", data.Name, err)
+			}
+		}
+	}
+	if t.Tests != nil {
+		for _, test := range t.Tests.Test {
+			for _, param := range test.Param {
+				if err := param.Validate(); err != nil {
+					return fmt.Errorf("test param %s: %v", param.Name, err)
+				}
+			}
+			for _, output := range test.Output {
+				if err := output.Validate(); err != nil {
+					return fmt.Errorf("test output %s: %v", output.Name, err)
+				}
+			}
+		}
+	}
+	if t.Citations != nil {
+		for _, citation := range t.Citations.Citation {
+			if err := citation.Validate(); err != nil {
+				return fmt.Errorf("citation %s: %v", citation.Value, err)
+			}
+		}
+	}
+	return nil
+}
@@ -3,26 +3,49 @@ package parser
 import (
 	"baryon/tool"
 	"fmt"
-	"log"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 // roxygen implements the functions to parse R function documentation
 // and obtain a Galaxy Tool.
-type roxygen struct{}
+type roxygen struct {
+	defaults Defaults
+}
 
 // NewRoxygen returns a New roxygen.
 func NewRoxygen() *roxygen {
 	return &roxygen{}
 }
 
-func (*roxygen) Parse(in []byte) (*tool.Tool, error) {
+// NewRoxygenWithDefaults returns a new roxygen applying defaults to the
+// tools.
+func NewRoxygenWithDefaults(defaults Defaults) *roxygen {
+	return &roxygen{defaults: defaults}
+}
+
+func (r *roxygen) Parse(in []byte) (*tool.Tool, error) {
 	var outtool tool.Tool
 	comment := obtainComment(in)
 	if len(comment) == 0 {
 		return nil, fmt.Errorf("Cannot parse roxygen comment.")
 	}
+	// The default container is there for the volume instructions, and
+	// replaced by the containers of the container instructions.
+	defaultContainers := 0
+	if r.defaults.Container != "" {
+		container := tool.Container{Type: r.defaults.ContainerType, Value: r.defaults.Container}
+		if container.Type == "" {
+			container.Type = "docker"
+		}
+		if err := container.Validate(); err != nil {
+			return nil, fmt.Errorf("[roxygen.Parse]: default container: %v", err)
+		}
+		outtool.Requirements = &tool.Requirements{Container: []tool.Container{container}}
+		defaultContainers = 1
+	}
 	commentEntries := getCommentEntries(comment)
 	for _, commentEntry := range commentEntries {
 		split := strings.Split(commentEntry, " ")
@@ -34,10 +57,35 @@ func (*roxygen) Parse(in []byte) (*tool.Tool, error) {
 			}
 			err := matcher(strings.Join(split[1:], " "), &outtool)
 			if err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf("[roxygen.Parse]: %v", err)
 			}
 		}
 	}
+	if containers := outtool.Requirements; containers != nil && len(containers.Container) > defaultContainers {
+		// The volumes of the default container not in the first declared
+		// container were declared before it, and apply to the declared
+		// containers.
+		if defaultContainers > 0 {
+			volumes := containers.Container[0].Volumes
+			volumes = volumes[:len(volumes)-len(containers.Container[1].Volumes)]
+			for i := 1; i < len(containers.Container) && len(volumes) > 0; i++ {
+				declared := &containers.Container[i]
+				declared.Volumes = append(slices.Clip(volumes), declared.Volumes...)
+			}
+		}
+		containers.Container = containers.Container[defaultContainers:]
+	}
+	if r.defaults.IdPrefix != "" && outtool.Id != "" && !strings.HasPrefix(outtool.Id, r.defaults.IdPrefix) {
+		outtool.Id = r.defaults.IdPrefix + outtool.Id
+	}
+	if r.defaults.Organization != "" {
+		if outtool.Creator == nil {
+			outtool.Creator = &tool.Creator{}
+		}
+		if outtool.Creator.Organization == nil {
+			outtool.Creator.Organization = &tool.Organization{Name: r.defaults.Organization}
+		}
+	}
 	return &outtool, nil
 }
 
@@ -189,6 +237,9 @@ var paramOptions map[string]ParamFunction = map[string]ParamFunction{
 	"required": func(t *tool.Param, arg string) { t.Optional = false },
 	"type":     func(t *tool.Param, arg string) { t.Type = arg },
 	"value":    func(t *tool.Param, arg string) { t.Value = arg },
+	"argument": func(t *tool.Param, arg string) { t.Argument = strings.TrimSpace(arg) },
+	// lint-ignore is read by the linter from the source.
+	"lint-ignore": func(t *tool.Param, arg string) {},
 	"options": func(t *tool.Param, arg string) {
 		for _, entry := range strings.Split(arg, ",") {
 			trimmedSpace := strings.TrimSpace(entry)
@@ -205,6 +256,7 @@ var paramOptions map[string]ParamFunction = map[string]ParamFunction{
 
 // descriptionInstruction is a map of functions used when parsing roxygen2 return.
 var descriptionInstruction map[string]ToolFunction = map[string]ToolFunction{
+	"lint-ignore": ignoreInstruction,
 	"id": func(t *tool.Tool, args string) error {
 		argList := strings.Split(args, ",")
 		if len(argList) != 1 {
@@ -221,6 +273,14 @@ var descriptionInstruction map[string]ToolFunction = map[string]ToolFunction{
 		t.Name = argList[0]
 		return nil
 	},
+	"category": func(t *tool.Tool, args string) error {
+		argList := strings.Split(args, ",")
+		if len(argList) != 1 || strings.TrimSpace(argList[0]) == "" {
+			return fmt.Errorf("descriptionInstruction[\"category\"]: exactly 1 arg")
+		}
+		t.Category = strings.TrimSpace(argList[0])
+		return nil
+	},
 	"container": func(t *tool.Tool, args string) error {
 		argList := strings.Split(args, ",")
 		if len(argList) < 1 {
@@ -254,6 +314,66 @@ var descriptionInstruction map[string]ToolFunction = map[string]ToolFunction{
 		t.Command.Value = arg
 		return nil
 	},
+	"citation": func(t *tool.Tool, args string) error {
+		args = strings.TrimSpace(args)
+		if len(args) == 0 {
+			return fmt.Errorf(
+				`descriptionInstruction["citation"]: argument not present.`)
+		}
+		citation := tool.Citation{Type: "doi", Value: args}
+		if citationType, value, ok := strings.Cut(args, ","); ok {
+			citation.Type = strings.TrimSpace(citationType)
+			citation.Value = strings.TrimSpace(value)
+		}
+		if err := citation.Validate(); err != nil {
+			return fmt.Errorf("descriptionInstruction[\"citation\"]: %v", err)
+		}
+		if t.Citations == nil {
+			t.Citations = &tool.Citations{}
+		}
+		t.Citations.Citation = append(t.Citations.Citation, citation)
+		return nil
+	},
+	"resources": func(t *tool.Tool, args string) error {
+		resources := tool.Resources{}
+		if t.Resources != nil {
+			resources = *t.Resources
+		}
+		for _, arg := range strings.Split(args, ",") {
+			if strings.TrimSpace(arg) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf(
+					"descriptionInstruction[\"resources\"]: %q is not name=value", arg)
+			}
+			var err error
+			switch name = strings.TrimSpace(name); name {
+			case "cpus":
+				resources.Cpus, err = strconv.Atoi(strings.TrimSpace(value))
+			case "memory":
+				resources.Memory, err = parseQuantity(value, map[string]int{
+					"": 1, "M": 1, "MB": 1, "G": 1024, "GB": 1024, "T": 1024 * 1024, "TB": 1024 * 1024,
+				})
+			case "time":
+				resources.Time, err = parseQuantity(value, map[string]int{
+					"": 1, "M": 1, "MIN": 1, "H": 60, "D": 24 * 60,
+				})
+			default:
+				return fmt.Errorf(
+					"descriptionInstruction[\"resources\"]: unknown resource %q", name)
+			}
+			if err != nil {
+				return fmt.Errorf("descriptionInstruction[\"resources\"]: %s: %v", name, err)
+			}
+		}
+		if err := resources.Validate(); err != nil {
+			return fmt.Errorf("descriptionInstruction[\"resources\"]: %v", err)
+		}
+		t.Resources = &resources
+		return nil
+	},
 	"volume": func(t *tool.Tool, args string) error {
 		args = strings.TrimSpace(args)
 		if len(args) == 0 {
@@ -269,7 +389,7 @@ var descriptionInstruction map[string]ToolFunction = map[string]ToolFunction{
 			HostPath:  strings.TrimSpace(argList[0]),
 			GuestPath: strings.TrimSpace(argList[1]),
 		}
-		if len(t.Requirements.Container) == 0 {
+		if t.Requirements == nil || len(t.Requirements.Container) == 0 {
 			return fmt.Errorf(
 				`descriptionInstruction["volume"]: there's no container.`)
 		}
@@ -281,10 +401,30 @@ var descriptionInstruction map[string]ToolFunction = map[string]ToolFunction{
 	},
 }
 
+// parseQuantity parses a number followed by one of the units, returning it
+// multiplied by the unit. Units are case insensitive.
+func parseQuantity(s string, units map[string]int) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	number := strings.TrimRight(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	unit, ok := units[s[len(number):]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in %q", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(number))
+	if err != nil {
+		return 0, err
+	}
+	return n * unit, nil
+}
+
 // ToolFunction is used to provide functions for Baryon Namespaces used, for
 // example, inside roxygen2 tags.
 type ToolFunction func(t *tool.Tool, args string) error
 
+// ignoreInstruction is the ToolFunction of the instructions read from the
+// source by other tools than the parser, as lint-ignore.
+func ignoreInstruction(*tool.Tool, string) error { return nil }
+
 // retrieveParser gets an instruction and instructions.
 // If it doesn't find the instruction inside the map, it returns an error.
 func retrieveParser(
@@ -300,6 +440,7 @@ func retrieveParser(
 
 // returnInstruction is a map of functions used when parsing roxygen2 return.
 var returnInstructions map[string]ToolFunction = map[string]ToolFunction{
+	"lint-ignore": ignoreInstruction,
 	"data": func(o *tool.Tool, args string) error {
 		argList := strings.Split(args, ",")
 		if len(argList) < 2 {
@@ -326,11 +467,49 @@ var returnInstructions map[string]ToolFunction = map[string]ToolFunction{
 		o.Outputs.Data = append(o.Outputs.Data, newData)
 		return nil
 	},
+	"test": func(o *tool.Tool, args string) error {
+		outputs := map[string]bool{}
+		if o.Outputs != nil {
+			for _, data := range o.Outputs.Data {
+				outputs[data.Name] = true
+			}
+		}
+		test := tool.Test{}
+		for _, arg := range strings.Split(args, ",") {
+			if strings.TrimSpace(arg) == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf(
+					"returnInstructions[\"test\"]: %q is not name=value", arg)
+			}
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if outputs[name] {
+				output := tool.TestOutput{Name: name, File: value}
+				if err := output.Validate(); err != nil {
+					return fmt.Errorf("returnInstructions[\"test\"]: %v", err)
+				}
+				test.Output = append(test.Output, output)
+				continue
+			}
+			param := tool.TestParam{Name: name, Value: value}
+			if err := param.Validate(); err != nil {
+				return fmt.Errorf("returnInstructions[\"test\"]: %v", err)
+			}
+			test.Param = append(test.Param, param)
+		}
+		if o.Tests == nil {
+			o.Tests = &tool.Tests{}
+		}
+		o.Tests.Test = append(o.Tests.Test, test)
+		return nil
+	},
 }
 
 // instructionRegex is used to match a Baryon Instruction and obtain its name
 // and the argument list.
-var instructionRegex = regexp.MustCompile(`((?:[[:alpha:]]|!)+)\ *(?:\(([^)]*)|)`)
+var instructionRegex = regexp.MustCompile(`((?:[[:alpha:]]|!)+(?:-[[:alpha:]]+)*)\ *(?:\(([^)]*)|)`)
 
 // Parse instruction into a tool.Tool.
 func parseInstruction(
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"baryon/tool"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -40,3 +43,240 @@ func Test_RoxygenParse(t *testing.T) {
 		t.Errorf("Expected error.")
 	}
 }
+
+func Test_RoxygenParseTest(t *testing.T) {
+	out, err := NewRoxygen().Parse([]byte(`#' @param n a number $B{type(integer);value(1)}
+#' @return $B{data(out.txt,txt);test(n=2, out.txt=expected.txt)}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if out.Tests == nil || len(out.Tests.Test) != 1 {
+		t.Fatalf("Got wrong tests: %+v", out.Tests)
+	}
+	test := out.Tests.Test[0]
+	if len(test.Param) != 1 || test.Param[0] != (tool.TestParam{Name: "n", Value: "2"}) {
+		t.Errorf("Got wrong params: %+v", test.Param)
+	}
+	if len(test.Output) != 1 || test.Output[0] != (tool.TestOutput{Name: "out.txt", File: "expected.txt"}) {
+		t.Errorf("Got wrong outputs: %+v", test.Output)
+	}
+	if err := returnInstructions["test"](&tool.Tool{}, "n"); err == nil {
+		t.Errorf("Expected error.")
+	}
+}
+
+func Test_RoxygenParseResources(t *testing.T) {
+	out, err := NewRoxygen().Parse([]byte(`#' @description D $B{resources(cpus=4, memory=8 G, time=2h)}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if *out.Resources != (tool.Resources{Cpus: 4, Memory: 8192, Time: 120}) {
+		t.Errorf("Got wrong resources: %+v", out.Resources)
+	}
+	for _, args := range []string{"cpus", "gpus=1", "memory=1X", "time=-1"} {
+		if err := descriptionInstruction["resources"](&tool.Tool{}, args); err == nil {
+			t.Errorf("Expected error for %s", args)
+		}
+	}
+}
+
+func Test_JSONParse(t *testing.T) {
+	jp := NewJSON()
+	out, err := jp.Parse([]byte(`{
+		"id": "t",
+		"command": {"value": "echo $n"},
+		"inputs": {"param": [{"type": "integer", "name": "n", "value": "1", "optional": true}]}
+	}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if out.Id != "t" || out.Command.Value != "echo $n" || out.Inputs.Param[0].Value != "1" {
+		t.Errorf("Got wrong tool: %+v", out)
+	}
+
+	for _, in := range []string{
+		``,
+		`{"unknown": 1}`,
+		`{"inputs": {"param": [{"type": "unknown", "name": "n"}]}}`,
+		`{"requirements": {"container": [{"type": "vm", "value": "x"}]}}`,
+		`{"tests": {"test": [{"output": [{"name": "out.txt", "file": ""}]}]}}`,
+	} {
+		if _, err := jp.Parse([]byte(in)); err == nil {
+			t.Errorf("Expected error for %s", in)
+		}
+	}
+}
+
+func Test_GalaxyParse(t *testing.T) {
+	dir := t.TempDir()
+	macros := `<macros>
+	<token name="@IMAGE@">lab/wc:1</token>
+	<xml name="requirements"><requirements><container type="docker">@IMAGE@</container></requirements></xml>
+</macros>`
+	if err := os.WriteFile(filepath.Join(dir, "macros.xml"), []byte(macros), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := NewGalaxy(dir).Parse([]byte(`<tool id="count" name="Count" version="1.0.0">
+	<macros><import>macros.xml</import></macros>
+	<expand macro="requirements"/>
+	<command><![CDATA[wc -l $input]]></command>
+	<inputs>
+		<param type="data" name="input"/>
+		<param type="integer" name="n" value="1" label="Count" help="The number of lines." optional="true"/>
+		<param type="text" name="legacy"><label>Legacy</label><help>Written as elements.</help><optional>true</optional></param>
+	</inputs>
+	<outputs><data format="txt" name="out"/></outputs>
+</tool>`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if out.Id != "count" || out.Requirements.Container[0].Value != "lab/wc:1" || out.Command.Value != "wc -l $input" {
+		t.Errorf("Got wrong tool: %+v", out)
+	}
+	if len(out.Inputs.Param) != 3 || out.Inputs.Param[0].Optional || !out.Inputs.Param[1].Optional || len(out.Outputs.Data) != 1 {
+		t.Errorf("Got wrong params and outputs: %+v %+v", out.Inputs, out.Outputs)
+	}
+	if n := out.Inputs.Param[1]; n.Label != "Count" || n.Help != "The number of lines." {
+		t.Errorf("Got wrong attributes: %+v", n)
+	}
+	if legacy := out.Inputs.Param[2]; legacy.Label != "Legacy" || legacy.Help != "Written as elements." || !legacy.Optional {
+		t.Errorf("Got wrong elements: %+v", legacy)
+	}
+
+	for _, in := range []string{
+		`<tool`,
+		`<tool><macros><import>missing.xml</import></macros></tool>`,
+		`<tool><macros/><expand macro="unknown"/></tool>`,
+	} {
+		if _, err := NewGalaxy(dir).Parse([]byte(in)); err == nil {
+			t.Errorf("Expected error for %s", in)
+		}
+	}
+}
+
+func Test_RoxygenParseDefaults(t *testing.T) {
+	defaults := Defaults{Container: "lab/r:1.0", Organization: "Lab", IdPrefix: "lab_"}
+	out, err := NewRoxygenWithDefaults(defaults).Parse(
+		[]byte(`#' @description A tool $B{id(tool);volume(/data:/data)}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if out.Id != "lab_tool" {
+		t.Errorf("Got wrong id: %s", out.Id)
+	}
+	containers := out.Requirements.Container
+	if len(containers) != 1 || containers[0].Value != "lab/r:1.0" || len(containers[0].Volumes) != 1 {
+		t.Errorf("Got wrong containers: %+v", containers)
+	}
+	if out.Creator == nil || out.Creator.Organization.Name != "Lab" {
+		t.Errorf("Got wrong creator: %+v", out.Creator)
+	}
+
+	out, err = NewRoxygenWithDefaults(defaults).Parse(
+		[]byte(`#' @description A tool $B{id(lab_tool);container(other:2)}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if out.Id != "lab_tool" {
+		t.Errorf("Got wrong id: %s", out.Id)
+	}
+	containers = out.Requirements.Container
+	if len(containers) != 1 || containers[0].Value != "other:2" {
+		t.Errorf("Got wrong containers: %+v", containers)
+	}
+
+	// The volumes placed before the container instructions apply to the
+	// declared containers, not to the default one.
+	out, err = NewRoxygenWithDefaults(defaults).Parse(
+		[]byte(`#' @description A tool $B{volume(/data:/data);container(a:1);volume(/ref:/ref);container(b:1)}`))
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	containers = out.Requirements.Container
+	if len(containers) != 2 || len(containers[0].Volumes) != 2 || containers[0].Volumes[0].GuestPath != "/data" ||
+		containers[0].Volumes[1].GuestPath != "/ref" || len(containers[1].Volumes) != 1 || containers[1].Volumes[0].GuestPath != "/data" {
+		t.Errorf("Got wrong containers: %+v", containers)
+	}
+}
+
+func Test_Entries(t *testing.T) {
+	in := []byte(`# Not roxygen.
+#' @description Count $B{id(count);
+#'   command(wc -l $input)}
+#' @param input.file the input $B{type(data);!;lint-ignore(short-help)}
+#' @return nothing
+f <- function(input.file) {}
+`)
+	entries := Entries(in)
+	if len(entries) != 3 {
+		t.Fatalf("Got wrong entries: %+v", entries)
+	}
+	description := entries[0]
+	if description.Tag != "description" || description.Text != "Count" {
+		t.Errorf("Got wrong description: %+v", description)
+	}
+	namespace := string(in[description.Namespace.Start:description.Namespace.End])
+	if namespace != "$B{id(count);\n#'   command(wc -l $input)}" {
+		t.Errorf("Got wrong namespace: %q", namespace)
+	}
+	if line, column := Position(in, description.Namespace.Start); line != 2 || column != 23 {
+		t.Errorf("Got wrong position: %d:%d", line, column)
+	}
+	param := entries[1]
+	want := []Instruction{{"type", "data"}, {"!", ""}, {"lint-ignore", "short-help"}}
+	if param.Name != "input__file" || !reflect.DeepEqual(param.Namespace.Instructions, want) {
+		t.Errorf("Got wrong param: %+v", param)
+	}
+	if entries[2].Namespace != nil || string(in[entries[2].Start:entries[2].End]) != "@return nothing" {
+		t.Errorf("Got wrong return: %+v", entries[2])
+	}
+	if _, err := NewRoxygen().Parse(in); err != nil {
+		t.Errorf("Got this error: %v", err)
+	}
+}
+
+func Test_Format(t *testing.T) {
+	in := []byte(`# Not roxygen $B{b;a}.
+#' @description Count $B{
+#'   command( wc -l $input );id(count);container(lab/wc:1.0);
+#'   name(Count)}
+#' @param input the input $B{ type(data) ; required ;}
+#' @param mode the mode $B{options(a, b);value(a);type(select)}
+#' @return $B{data(output, txt,);test(input=in.txt, output = out.txt);}
+f <- function(input, mode) {}
+`)
+	want := `# Not roxygen $B{b;a}.
+#' @description Count $B{
+#'     id(count);
+#'     name(Count);
+#'     container(lab/wc:1.0);
+#'     command(wc -l $input);
+#' }
+#' @param input the input $B{!;type(data)}
+#' @param mode the mode $B{type(select);value(a);options(a,b)}
+#' @return $B{data(output,txt);test(input=in.txt,output=out.txt)}
+f <- function(input, mode) {}
+`
+	out, err := Format(in, DefaultFormatStyle)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if string(out) != want {
+		t.Fatalf("Got wrong format:\n%s", out)
+	}
+	if again, err := Format(out, DefaultFormatStyle); err != nil || string(again) != want {
+		t.Errorf("Format is not idempotent: %v\n%s", err, again)
+	}
+
+	// The volume applies to the first container only: its order is kept.
+	in = []byte(`#' @description x $B{container(a:1);volume(/a:/b);container(b:1);id(x);command(x)}
+#' @param p a param $B{type(text);!}
+`)
+	want = `#' @description x $B{container(a:1);volume(/a:/b);container(b:1);id(x);command(x)}
+#' @param p a param $B{required;type(text)}
+`
+	out, err = Format(in, FormatStyle{Required: "required", Width: 100})
+	if err != nil || string(out) != want {
+		t.Errorf("Got wrong format: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Entry is a roxygen2 tag of an R source, as read by the roxygen parser.
+type Entry struct {
+	// Tag is the name of the tag, without @.
+	Tag string
+	// Name is the name of the parameter of a param tag, as named in the
+	// tool.
+	Name string
+	// Text is the content of the tag after its name, without its Baryon
+	// Namespace.
+	Text string
+	// Namespace is the Baryon Namespace of the tag, nil when there is none.
+	Namespace *Namespace
+	// Start and End are the offsets of the tag in the source, from its @
+	// to the end of its content.
+	Start, End int
+}
+
+// Namespace is a Baryon Namespace of an R source.
+type Namespace struct {
+	// Content is the text between the braces, without the roxygen2 line
+	// prefixes.
+	Content string
+	// Instructions are the instructions of Content.
+	Instructions []Instruction
+	// Start and End are the offsets of the namespace in the source, from
+	// its $ to after its closing brace.
+	Start, End int
+}
+
+// Instruction is an instruction of a Baryon Namespace.
+type Instruction struct {
+	// Name is the name of the instruction, as type or !.
+	Name string
+	// Args are the arguments between the parentheses.
+	Args string
+}
+
+// Entries returns the roxygen2 tags of the R source in, in their order.
+func Entries(in []byte) []Entry {
+	comment, positions := commentPositions(in)
+	entries := []Entry{}
+	for _, span := range commentEntryRegex.FindAllStringIndex(comment, -1) {
+		content := comment[span[0]:span[1]]
+		tag, rest, _ := strings.Cut(content, " ")
+		entry := Entry{
+			Tag:   strings.TrimLeft(tag, "@"),
+			Start: positions.source(span[0]),
+			End:   positions.source(span[0] + len(strings.TrimRight(content, " \n"))),
+		}
+		if entry.Tag == "param" {
+			name, text, _ := strings.Cut(rest, " ")
+			entry.Name = strings.Replace(name, ".", "__", -1)
+			rest = text
+		}
+		entry.Text = rest
+		if match := baryonNamespaceRegex.FindStringSubmatchIndex(rest); match != nil {
+			offset := span[0] + len(content) - len(rest)
+			entry.Namespace = &Namespace{
+				Content:      rest[match[2]:match[3]],
+				Instructions: splitInstructions(rest[match[2]:match[3]]),
+				Start:        positions.source(offset + match[0]),
+				End:          positions.source(offset+match[1]-1) + 1,
+			}
+			entry.Text = rest[:match[0]] + rest[match[1]:]
+		}
+		entry.Text = strings.TrimSpace(entry.Text)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// splitInstructions returns the instructions of the content of a Baryon
+// Namespace, as parseInstruction reads them.
+func splitInstructions(content string) []Instruction {
+	instructions := []Instruction{}
+	for _, instruction := range strings.Split(content, ";") {
+		match := instructionRegex.FindStringSubmatch(strings.TrimSpace(instruction))
+		if len(match) < 3 {
+			continue
+		}
+		instructions = append(instructions, Instruction{
+			Name: strings.TrimSpace(match[1]),
+			Args: match[2],
+		})
+	}
+	return instructions
+}
+
+// Instructions returns the names of the instructions of the Baryon
+// Namespaces of tag, sorted, nil for the tags whose namespaces are ignored.
+func Instructions(tag string) []string {
+	names := []string{}
+	switch tag {
+	case "param":
+		for name := range paramOptions {
+			names = append(names, name)
+		}
+	case "description":
+		for name := range descriptionInstruction {
+			names = append(names, name)
+		}
+	case "return":
+		for name := range returnInstructions {
+			names = append(names, name)
+		}
+	default:
+		return nil
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Position returns the line and the column, starting at 1, of the byte at
+// offset in the source in.
+func Position(in []byte, offset int) (line, column int) {
+	offset = min(max(offset, 0), len(in))
+	before := in[:offset]
+	line = strings.Count(string(before), "\n") + 1
+	column = offset - (strings.LastIndex(string(before), "\n") + 1) + 1
+	return line, column
+}
+
+// commentLine maps the content of a roxygen line in the comment returned
+// by obtainComment to the source.
+type commentLine struct {
+	// comment and source are the offsets of the content in the comment
+	// and in the source.
+	comment, source int
+}
+
+// commentLines maps the offsets of a comment to the offsets of its source.
+type commentLines []commentLine
+
+// source returns the offset in the source of the offset in the comment.
+// The newline ending a line of the comment is the end of the roxygen line.
+func (lines commentLines) source(offset int) int {
+	i := sort.Search(len(lines), func(i int) bool { return lines[i].comment > offset }) - 1
+	if i < 0 {
+		return 0
+	}
+	return lines[i].source + offset - lines[i].comment
+}
+
+// commentPositions returns the comment of in, as obtainComment, with the
+// positions of its lines in the source.
+func commentPositions(in []byte) (string, commentLines) {
+	comment := &strings.Builder{}
+	lines := commentLines{}
+	offset := 0
+	for _, line := range strings.SplitAfter(string(in), "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		if match := roxygenLineRegex.FindStringSubmatchIndex(text); match != nil {
+			lines = append(lines, commentLine{comment: comment.Len(), source: offset + match[2]})
+			comment.WriteString(text[match[2]:match[3]] + "\n")
+		}
+		offset += len(line)
+	}
+	return comment.String(), lines
+}
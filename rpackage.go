@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rPackageRoot returns the root directory of the R package containing the
+// file at path: the closest parent directory having a DESCRIPTION file, or
+// an empty string when there is none.
+func rPackageRoot(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	for {
+		if stat, err := os.Stat(filepath.Join(dir, "DESCRIPTION")); err == nil && !stat.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// rDescription returns the fields of the DESCRIPTION file of the R package
+// containing the file at path, or nil when there is none.
+func rDescription(path string) map[string]string {
+	root := rPackageRoot(path)
+	if root == "" {
+		return nil
+	}
+	description, err := os.ReadFile(filepath.Join(root, "DESCRIPTION"))
+	if err != nil {
+		return nil
+	}
+	fields := map[string]string{}
+	field := ""
+	for _, line := range strings.Split(string(description), "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			// Continuation of the previous field.
+			if field != "" {
+				fields[field] += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			field = strings.TrimSpace(name)
+			fields[field] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// rPackage returns the name of the R package containing the file at path,
+// or an empty string when there is none.
+func rPackage(path string) string {
+	return rDescription(path)["Package"]
+}
+
+// rBasePackages are the packages shipped with R.
+var rBasePackages = map[string]bool{
+	"R": true, "base": true, "compiler": true, "datasets": true, "graphics": true,
+	"grDevices": true, "grid": true, "methods": true, "parallel": true, "splines": true,
+	"stats": true, "stats4": true, "tcltk": true, "tools": true, "utils": true,
+}
+
+// rDependencies returns the packages the R package containing the file at
+// path depends upon or imports, without the rBasePackages.
+func rDependencies(path string) []string {
+	description := rDescription(path)
+	dependencies := []string{}
+	for _, field := range []string{"Depends", "Imports"} {
+		for _, dependency := range strings.Split(description[field], ",") {
+			// Drops the version constraint, as in "dplyr (>= 1.0)".
+			name, _, _ := strings.Cut(dependency, "(")
+			name = strings.TrimSpace(name)
+			if name != "" && !rBasePackages[name] {
+				dependencies = append(dependencies, name)
+			}
+		}
+	}
+	return dependencies
+}
+
+// rExportRegex matches the export and exportPattern directives of a
+// NAMESPACE file.
+var rExportRegex = regexp.MustCompile(`(?s)\b(export|exportPattern)\s*\(([^)]*)\)`)
+
+// rExported returns whether the R package containing the file at path
+// exports a function, by name, from the directives of its NAMESPACE file.
+// Nothing is exported outside of a package.
+func rExported(path string) func(name string) bool {
+	names := map[string]bool{}
+	patterns := []*regexp.Regexp{}
+	if root := rPackageRoot(path); root != "" {
+		namespace, _ := os.ReadFile(filepath.Join(root, "NAMESPACE"))
+		for _, match := range rExportRegex.FindAllStringSubmatch(string(namespace), -1) {
+			for _, argument := range strings.Split(match[2], ",") {
+				argument = strings.Trim(strings.TrimSpace(argument), "\"'`")
+				if match[1] == "export" {
+					names[argument] = true
+				} else if pattern, err := regexp.Compile(strings.ReplaceAll(argument, `\\`, `\`)); err == nil {
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+	}
+	return func(name string) bool {
+		if names[name] {
+			return true
+		}
+		for _, pattern := range patterns {
+			if pattern.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"baryon/parser"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rFunctionRegex matches the lines defining a top-level R function, up to
+// the parenthesis opening its arguments.
+var rFunctionRegex = regexp.MustCompile(`^([A-Za-z.][A-Za-z0-9._]*)\s*(?:<-|=)\s*function\s*\(`)
+
+// rArgument is a formal argument of an R function.
+type rArgument struct {
+	// name is the name of the argument.
+	name string
+	// value is the default value of the argument, as written.
+	value string
+	// hasValue reports whether the argument has a default value.
+	hasValue bool
+}
+
+// rFunction is a top-level R function of a source.
+type rFunction struct {
+	// name is the name of the function.
+	name string
+	// line is the index of the line defining the function.
+	line int
+	// arguments are the formal arguments of the function.
+	arguments []rArgument
+}
+
+// rFunctions returns the top-level R functions of the lines of a source.
+func rFunctions(lines []string) []rFunction {
+	functions := []rFunction{}
+	for i, line := range lines {
+		match := rFunctionRegex.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		rest := strings.Join(append([]string{line[match[1]:]}, lines[i+1:]...), "\n")
+		functions = append(functions, rFunction{
+			name:      line[match[2]:match[3]],
+			line:      i,
+			arguments: rArguments(rest),
+		})
+	}
+	return functions
+}
+
+// rArguments returns the formal arguments written in s, up to the
+// parenthesis closing them.
+func rArguments(s string) []rArgument {
+	arguments := []rArgument{}
+	add := func(argument string) {
+		argument = strings.TrimSpace(argument)
+		if argument == "" {
+			return
+		}
+		name, value, ok := cutTopLevel(argument, '=')
+		arguments = append(arguments, rArgument{
+			name:     strings.Trim(strings.TrimSpace(name), "`"),
+			value:    strings.TrimSpace(value),
+			hasValue: ok,
+		})
+	}
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'', '`':
+			i = skipString(s, i)
+		case '#':
+			// A comment, up to the end of the line.
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				add(stripComments(s[start:i]))
+				return arguments
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				add(stripComments(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return arguments
+}
+
+// skipString returns the index of the quote closing the R string starting
+// at the quote s[i], or the last index of s when it is not closed.
+func skipString(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// stripComments returns the R code s without its comments.
+func stripComments(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		for j := 0; j < len(line); j++ {
+			switch line[j] {
+			case '"', '\'', '`':
+				j = skipString(line, j)
+			case '#':
+				line = line[:j]
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cutTopLevel cuts the R code s around the first separator outside of the
+// strings and the brackets.
+func cutTopLevel(s string, separator byte) (before, after string, found bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			i = skipString(s, i)
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == separator && depth == 0:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+var (
+	// rIntegerRegex matches the R integer literals.
+	rIntegerRegex = regexp.MustCompile(`^-?[0-9]+L?$`)
+	// rFloatRegex matches the R double literals.
+	rFloatRegex = regexp.MustCompile(`^-?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?$`)
+	// rStringRegex matches the R string literals without escape.
+	rStringRegex = regexp.MustCompile(`^(?:"([^"\\]*)"|'([^'\\]*)')$`)
+	// rVectorRegex matches the calls to c.
+	rVectorRegex = regexp.MustCompile(`^c\s*\((.*)\)$`)
+	// dataArgumentRegex matches the names of the arguments likely to be
+	// files.
+	dataArgumentRegex = regexp.MustCompile(`(?i)(?:file|path|dir|^input$|^in$|fast[aq]|csv|tsv|bam)`)
+)
+
+// rLiteral returns the value of the R literal s, reporting whether s is a
+// number or a string without escape.
+func rLiteral(s string) (string, bool) {
+	if match := rStringRegex.FindStringSubmatch(s); match != nil {
+		return match[1] + match[2], true
+	}
+	if rIntegerRegex.MatchString(s) {
+		return strings.TrimSuffix(s, "L"), true
+	}
+	return s, rFloatRegex.MatchString(s)
+}
+
+// safeArgument reports whether s can be written as the argument of an
+// instruction, as an element of a list when list is true.
+func safeArgument(s string, list bool) bool {
+	forbidden := ";)}@\n"
+	if list {
+		forbidden += ","
+	}
+	return s == strings.TrimSpace(s) && !strings.ContainsAny(s, forbidden)
+}
+
+// inferParam returns the instructions of the @param of the argument,
+// inferred from its name and its default value:
+//
+//   - an argument without default value, or NULL or NA, is required, of
+//     type data when its name looks like a file, and text otherwise;
+//   - TRUE and FALSE are boolean, the integer literals integer, the other
+//     numbers float, and the strings text;
+//   - a vector of literals, as for match.arg, is a select of its elements,
+//     the first by default;
+//   - another expression is a text of its code.
+func inferParam(argument rArgument) []parser.Instruction {
+	value := argument.value
+	required := func() []parser.Instruction {
+		paramType := "text"
+		if dataArgumentRegex.MatchString(argument.name) {
+			paramType = "data"
+		}
+		return []parser.Instruction{{Name: "!"}, {Name: "type", Args: paramType}}
+	}
+	typed := func(paramType string, value string) []parser.Instruction {
+		if !safeArgument(value, false) {
+			return required()
+		}
+		return []parser.Instruction{{Name: "type", Args: paramType}, {Name: "value", Args: value}}
+	}
+	switch {
+	case !argument.hasValue || value == "NULL" || value == "NA":
+		return required()
+	case value == "TRUE" || value == "T":
+		return typed("boolean", "true")
+	case value == "FALSE" || value == "F":
+		return typed("boolean", "false")
+	case rIntegerRegex.MatchString(value):
+		return typed("integer", strings.TrimSuffix(value, "L"))
+	case rFloatRegex.MatchString(value):
+		return typed("float", value)
+	case rStringRegex.MatchString(value):
+		literal, _ := rLiteral(value)
+		return typed("text", literal)
+	}
+	if match := rVectorRegex.FindStringSubmatch(value); match != nil {
+		options := []string{}
+		for _, element := range rArguments(match[1] + ")") {
+			literal, ok := rLiteral(element.name)
+			if element.hasValue || !ok || !safeArgument(literal, true) || literal == "" {
+				return typed("text", value)
+			}
+			options = append(options, literal)
+		}
+		if len(options) > 0 {
+			return []parser.Instruction{
+				{Name: "type", Args: "select"},
+				{Name: "value", Args: options[0]},
+				{Name: "options", Args: strings.Join(options, ",")},
+			}
+		}
+	}
+	return typed("text", value)
+}
+
+// roxygenTag is a roxygen2 tag of a block of roxygen2 lines.
+type roxygenTag struct {
+	// name is the name of the tag, without @.
+	name string
+	// param is the name of the parameter of a param tag.
+	param string
+	// start is the index of the first line of the tag in the block, and
+	// end the index after its last line that is not empty.
+	start, end int
+	// hasNamespace reports whether the tag has a Baryon Namespace.
+	hasNamespace bool
+}
+
+// roxygenTagRegex matches the roxygen2 lines starting a tag.
+var roxygenTagRegex = regexp.MustCompile(`^#' ?\s*@([[:alpha:]]+)\s*(\S*)`)
+
+// roxygenTags returns the tags of the block of roxygen2 lines.
+func roxygenTags(block []string) []roxygenTag {
+	tags := []roxygenTag{}
+	for i, line := range block {
+		if match := roxygenTagRegex.FindStringSubmatch(line); match != nil {
+			tags = append(tags, roxygenTag{name: match[1], start: i, end: i + 1})
+			if match[1] == "param" {
+				tags[len(tags)-1].param = match[2]
+			}
+			continue
+		}
+		if len(tags) > 0 && strings.TrimSpace(strings.TrimPrefix(line, "#'")) != "" {
+			tags[len(tags)-1].end = i + 1
+		}
+	}
+	for i := range tags {
+		tags[i].hasNamespace = strings.Contains(strings.Join(block[tags[i].start:tags[i].end], "\n"), "$B{")
+	}
+	return tags
+}
+
+// scaffoldNamespace returns the line with a namespace of tag with the
+// instructions added, on it when it fits, and otherwise on the next lines.
+func scaffoldNamespace(line string, tag string, instructions []parser.Instruction) string {
+	namespace := parser.FormatNamespace(tag, instructions, len(line)+2, parser.DefaultFormatStyle)
+	if !strings.Contains(namespace, "\n") {
+		return line + " " + namespace
+	}
+	return line + "\n#' " + parser.FormatNamespace(tag, instructions, len("#' ")+1, parser.DefaultFormatStyle)
+}
+
+// scaffold returns the lines of the R source at path with Baryon
+// Namespaces proposed for the exported functions: the functions whose
+// roxygen2 block has an @export tag, or exported by exported. It returns
+// the names of the functions changed.
+func scaffold(path string, lines []string, exported func(name string) bool, c *config) ([]string, []string) {
+	out := []string{}
+	changed := []string{}
+	next := 0
+	for _, function := range rFunctions(lines) {
+		start := function.line
+		for start > next && strings.HasPrefix(lines[start-1], "#'") {
+			start--
+		}
+		block := lines[start:function.line]
+		tags := roxygenTags(block)
+		isExported := exported(function.name)
+		for _, tag := range tags {
+			isExported = isExported || tag.name == "export"
+		}
+		out = append(out, lines[next:start]...)
+		next = function.line
+		if !isExported {
+			out = append(out, block...)
+			continue
+		}
+		scaffolded, ok := scaffoldBlock(path, block, tags, function, c)
+		out = append(out, scaffolded...)
+		if ok {
+			changed = append(changed, function.name)
+		}
+	}
+	return append(out, lines[next:]...), changed
+}
+
+// scaffoldBlock returns the roxygen2 block of the function with the
+// Baryon Namespaces it lacks, reporting whether it changed.
+func scaffoldBlock(path string, block []string, tags []roxygenTag, function rFunction, c *config) ([]string, bool) {
+	// before are the lines inserted before the lines of the block, by
+	// index, and replaced the lines replaced.
+	before := map[int][]string{}
+	replaced := map[int]string{}
+	changed := false
+	addNamespace := func(tag roxygenTag, instructions []parser.Instruction) {
+		if tag.hasNamespace {
+			return
+		}
+		replaced[tag.end-1] = scaffoldNamespace(block[tag.end-1], tag.name, instructions)
+		changed = true
+	}
+	insert := func(at int, line string, tag string, instructions []parser.Instruction) {
+		before[at] = append(before[at], scaffoldNamespace(line, tag, instructions))
+		changed = true
+	}
+
+	command := "Rscript /scripts/" + filepath.Base(path)
+	for _, argument := range function.arguments {
+		if argument.name != "..." {
+			command += " $" + strings.ReplaceAll(argument.name, ".", "__")
+		}
+	}
+	description := []parser.Instruction{
+		{Name: "id", Args: strings.ReplaceAll(function.name, ".", "_")},
+		{Name: "name", Args: function.name},
+		{Name: "command", Args: command},
+	}
+	if c.Container == "" {
+		description = append(description, parser.Instruction{Name: "container", Args: defaultContainer})
+	}
+	returns := []parser.Instruction{{Name: "data", Args: "result.txt,txt"}}
+
+	// The tags are inserted after the description and the parameters, or
+	// before the first tag.
+	anchor := len(block)
+	if len(tags) > 0 {
+		anchor = tags[0].start
+	}
+	hasDescription, hasReturn := false, false
+	params := map[string]roxygenTag{}
+	for _, tag := range tags {
+		switch tag.name {
+		case "description":
+			hasDescription = true
+			anchor = max(anchor, tag.end)
+			addNamespace(tag, description)
+		case "param":
+			params[tag.param] = tag
+			anchor = max(anchor, tag.end)
+		case "return":
+			hasReturn = true
+			addNamespace(tag, returns)
+		}
+	}
+	if !hasDescription {
+		at := len(block)
+		if len(tags) > 0 {
+			at = tags[0].start
+		}
+		// The introduction of the block is a title and a description,
+		// separated by an empty line.
+		paragraphs := []string{""}
+		for _, line := range block[:at] {
+			text := strings.TrimSpace(strings.TrimPrefix(line, "#'"))
+			switch {
+			case text != "":
+				paragraphs[len(paragraphs)-1] = strings.TrimSpace(paragraphs[len(paragraphs)-1] + " " + text)
+			case paragraphs[len(paragraphs)-1] != "":
+				paragraphs = append(paragraphs, "")
+			}
+		}
+		text := paragraphs[min(1, len(paragraphs)-1)]
+		if text == "" {
+			text = paragraphs[0]
+		}
+		if len(block) == 0 {
+			text = function.name
+			before[0] = append(before[0], "#' "+text, "#'")
+		}
+		insert(at, "#' @description "+text, "description", description)
+	}
+	for _, argument := range function.arguments {
+		if argument.name == "..." {
+			continue
+		}
+		if tag, ok := params[argument.name]; ok {
+			addNamespace(tag, inferParam(argument))
+			continue
+		}
+		insert(anchor, "#' @param "+argument.name, "param", inferParam(argument))
+	}
+	if !hasReturn {
+		insert(anchor, "#' @return", "return", returns)
+	}
+	if !changed {
+		return block, false
+	}
+	out := []string{}
+	for i := 0; i <= len(block); i++ {
+		for _, line := range before[i] {
+			out = append(out, strings.Split(line, "\n")...)
+		}
+		if i == len(block) {
+			break
+		}
+		if line, ok := replaced[i]; ok {
+			out = append(out, strings.Split(line, "\n")...)
+			continue
+		}
+		out = append(out, block[i])
+	}
+	return out, true
+}
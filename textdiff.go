@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes of a
+// unified diff.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff of the lines a and b, named from and
+// to, empty when they are equal.
+func unifiedDiff(from, to string, a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	// edits are the lines of the diff, prefixed by ' ', '-' or '+'.
+	edits := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, " "+a[i])
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, "-"+a[i])
+			i++
+		default:
+			edits = append(edits, "+"+b[j])
+			j++
+		}
+	}
+
+	builder := &strings.Builder{}
+	// start and end are the offsets of the edits of the hunk, and aLine
+	// and bLine the lines of a and b before start.
+	aLine, bLine := 0, 0
+	for start := 0; start < len(edits); {
+		if edits[start][0] == ' ' {
+			start, aLine, bLine = start+1, aLine+1, bLine+1
+			continue
+		}
+		hunkStart := max(start-diffContext, 0)
+		aStart, bStart := aLine-(start-hunkStart), bLine-(start-hunkStart)
+		end, unchanged := start, 0
+		for end < len(edits) && unchanged <= 2*diffContext {
+			if edits[end][0] == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		end -= max(unchanged-diffContext, 0)
+		if builder.Len() == 0 {
+			fmt.Fprintf(builder, "--- %s\n+++ %s\n", from, to)
+		}
+		aCount, bCount := 0, 0
+		for _, edit := range edits[hunkStart:end] {
+			if edit[0] != '+' {
+				aCount++
+			}
+			if edit[0] != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, edit := range edits[hunkStart:end] {
+			builder.WriteString(edit + "\n")
+		}
+		aLine, bLine = aStart+aCount, bStart+bCount
+		start = end
+	}
+	return builder.String()
+}
+
+// hunkRange returns the range of a hunk of a unified diff, of count lines
+// after the line start.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,277 @@
+package tool
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bump is the part of the version of a tool a Change increments.
+type Bump int
+
+const (
+	// Patch is a change keeping the interface of the tool.
+	Patch Bump = iota
+	// Minor is an addition to the interface of the tool, keeping the
+	// workflows using it working.
+	Minor
+	// Major is a change of the interface of the tool breaking the
+	// workflows using it.
+	Major
+)
+
+// String implements fmt.Stringer.
+func (b Bump) String() string {
+	return [...]string{"patch", "minor", "major"}[b]
+}
+
+// Change is a difference between two versions of a tool.
+type Change struct {
+	// Subject is what changed, as "param input" or "output result.txt".
+	Subject string
+	// Message describes the change.
+	Message string
+	// Bump is the part of the version the change increments.
+	Bump Bump
+}
+
+// Breaking reports whether the change breaks the workflows using the tool.
+func (c Change) Breaking() bool {
+	return c.Bump == Major
+}
+
+// Diff returns the changes of the interface of the tool from old to new:
+// its id and name, its params, its outputs and its containers, with the
+// changes of the command, the description and the help as Patch.
+//
+// A param removed and a param added with the same type, value and options
+// are reported as renamed.
+func Diff(old, new *Tool) []Change {
+	changes := []Change{}
+	add := func(subject string, bump Bump, format string, args ...any) {
+		changes = append(changes, Change{Subject: subject, Message: fmt.Sprintf(format, args...), Bump: bump})
+	}
+	if old.Id != new.Id {
+		add("tool", Major, "id changed from %s to %s", old.Id, new.Id)
+	}
+	if old.Name != new.Name {
+		add("tool", Patch, "name changed from %q to %q", old.Name, new.Name)
+	}
+	if old.Description != new.Description {
+		add("tool", Patch, "description changed")
+	}
+	if command(old) != command(new) {
+		add("tool", Patch, "command changed")
+	}
+
+	oldParams, newParams := params(old), params(new)
+	removed, added := []Param{}, []Param{}
+	for _, param := range oldParams {
+		if !slices.ContainsFunc(newParams, func(p Param) bool { return p.Name == param.Name }) {
+			removed = append(removed, param)
+		}
+	}
+	for _, param := range newParams {
+		i := slices.IndexFunc(oldParams, func(p Param) bool { return p.Name == param.Name })
+		if i < 0 {
+			added = append(added, param)
+			continue
+		}
+		diffParam(oldParams[i], param, add)
+	}
+	for _, param := range removed {
+		renamed := -1
+		for i, candidate := range added {
+			if candidate.Type == param.Type && candidate.Value == param.Value && reflect.DeepEqual(optionValues(candidate), optionValues(param)) {
+				if renamed >= 0 {
+					renamed = -1
+					break
+				}
+				renamed = i
+			}
+		}
+		if renamed < 0 {
+			add("param "+param.Name, Major, "removed")
+			continue
+		}
+		add("param "+param.Name, Major, "renamed to %s", added[renamed].Name)
+		diffParam(param, added[renamed], func(_ string, bump Bump, format string, args ...any) {
+			add("param "+added[renamed].Name, bump, format, args...)
+		})
+		added = slices.Delete(added, renamed, renamed+1)
+	}
+	for _, param := range added {
+		if !param.Optional && param.Value == "" && param.Type != "boolean" {
+			add("param "+param.Name, Major, "added, required without default value")
+		} else {
+			add("param "+param.Name, Minor, "added")
+		}
+	}
+
+	oldData, newData := data(old), data(new)
+	for _, output := range oldData {
+		i := slices.IndexFunc(newData, func(d Data) bool { return d.Name == output.Name })
+		switch {
+		case i < 0:
+			add("output "+output.Name, Major, "removed")
+		case newData[i].Format != output.Format:
+			add("output "+output.Name, Major, "format changed from %s to %s", output.Format, newData[i].Format)
+		case newData[i].Label != output.Label:
+			add("output "+output.Name, Patch, "label changed from %q to %q", output.Label, newData[i].Label)
+		}
+	}
+	for _, output := range newData {
+		if !slices.ContainsFunc(oldData, func(d Data) bool { return d.Name == output.Name }) {
+			add("output "+output.Name, Minor, "added, of format %s", output.Format)
+		}
+	}
+
+	oldContainers, newContainers := containers(old), containers(new)
+	for i := 0; i < max(len(oldContainers), len(newContainers)); i++ {
+		switch {
+		case i >= len(newContainers):
+			add("container", Patch, "%s removed", oldContainers[i])
+		case i >= len(oldContainers):
+			add("container", Patch, "%s added", newContainers[i])
+		case oldContainers[i] != newContainers[i]:
+			add("container", Patch, "changed from %s to %s", oldContainers[i], newContainers[i])
+		}
+	}
+	if !reflect.DeepEqual(resources(old), resources(new)) {
+		add("tool", Patch, "resources changed")
+	}
+	return changes
+}
+
+// diffParam adds the changes of a param from old to new.
+func diffParam(old, new Param, add func(subject string, bump Bump, format string, args ...any)) {
+	subject := "param " + new.Name
+	if old.Type != new.Type {
+		add(subject, Major, "type changed from %s to %s", old.Type, new.Type)
+	}
+	switch {
+	case old.Optional && !new.Optional:
+		add(subject, Major, "now required")
+	case !old.Optional && new.Optional:
+		add(subject, Minor, "now optional")
+	}
+	if old.Value != new.Value {
+		add(subject, Patch, "default value changed from %q to %q", old.Value, new.Value)
+	}
+	oldOptions, newOptions := optionValues(old), optionValues(new)
+	for _, option := range oldOptions {
+		if !slices.Contains(newOptions, option) {
+			add(subject, Major, "option %s removed", option)
+		}
+	}
+	for _, option := range newOptions {
+		if !slices.Contains(oldOptions, option) {
+			add(subject, Minor, "option %s added", option)
+		}
+	}
+	if old.Argument != new.Argument {
+		add(subject, Patch, "argument changed from %q to %q", old.Argument, new.Argument)
+	}
+	if old.Label != new.Label || old.Help != new.Help {
+		add(subject, Patch, "help changed")
+	}
+}
+
+// command returns the command of t, empty when it has none.
+func command(t *Tool) string {
+	if t.Command == nil {
+		return ""
+	}
+	return t.Command.Value
+}
+
+// params returns the params of t.
+func params(t *Tool) []Param {
+	if t.Inputs == nil {
+		return nil
+	}
+	return t.Inputs.Param
+}
+
+// optionValues returns the values of the options of p.
+func optionValues(p Param) []string {
+	values := []string{}
+	for _, option := range p.Options {
+		values = append(values, option.Value)
+	}
+	return values
+}
+
+// data returns the data outputs of t.
+func data(t *Tool) []Data {
+	if t.Outputs == nil {
+		return nil
+	}
+	return t.Outputs.Data
+}
+
+// containers returns the containers of t, as image (type), the image
+// trimmed of the indentation of the volumes following it in a tool XML.
+func containers(t *Tool) []string {
+	if t.Requirements == nil {
+		return nil
+	}
+	containers := []string{}
+	for _, container := range t.Requirements.Container {
+		containers = append(containers, fmt.Sprintf("%s (%s)", strings.TrimSpace(container.Value), container.Type))
+	}
+	return containers
+}
+
+// resources returns the resources of t, from Tool.Resources or from the
+// resource requirements of its tool XML.
+func resources(t *Tool) any {
+	if t.Resources != nil {
+		return *t.Resources
+	}
+	if t.Requirements != nil && len(t.Requirements.Resource) > 0 {
+		return t.Requirements.Resource
+	}
+	return nil
+}
+
+// BumpOf returns the greatest Bump of changes, Patch when there is none.
+func BumpOf(changes []Change) Bump {
+	bump := Patch
+	for _, change := range changes {
+		bump = max(bump, change.Bump)
+	}
+	return bump
+}
+
+// NextVersion returns the version following version after changes: the
+// major, minor or patch number of its major.minor.patch prefix incremented
+// by the greatest Bump of the changes, the numbers after it reset, and its
+// suffix, as +galaxy1, dropped. It returns version when there is no change.
+func NextVersion(version string, changes []Change) (string, error) {
+	if len(changes) == 0 {
+		return version, nil
+	}
+	prefix, _, _ := strings.Cut(version, "+")
+	prefix, _, _ = strings.Cut(prefix, "-")
+	parts := strings.Split(prefix, ".")
+	if len(parts) > 3 {
+		return "", fmt.Errorf("[NextVersion]: %q is not a major.minor.patch version", version)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("[NextVersion]: %q is not a major.minor.patch version", version)
+		}
+		numbers[i] = n
+	}
+	i := 2 - int(BumpOf(changes))
+	numbers[i]++
+	for j := i + 1; j < len(numbers); j++ {
+		numbers[j] = 0
+	}
+	return fmt.Sprintf("%d.%d.%d", numbers[0], numbers[1], numbers[2]), nil
+}
@@ -0,0 +1,9 @@
+package tool
+
+import _ "embed"
+
+// JSONSchema is the JSON Schema of the JSON and YAML representations of a
+// Tool.
+//
+//go:embed tool.schema.json
+var JSONSchema []byte
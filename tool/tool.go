@@ -3,6 +3,7 @@ package tool
 import (
 	"encoding/xml"
 	"fmt"
+	"slices"
 )
 
 // Validable represents a validable object.
@@ -13,23 +14,33 @@ type Validable interface{ Validate() error }
 // You can find the current schema here:
 // https://docs.galaxyproject.org/en/master/dev/schema.html
 type Tool struct {
-	XMLName xml.Name `xml:"tool"`
+	XMLName xml.Name `xml:"tool" json:"-" yaml:"-"`
 	// The value is displayed in the tool menu immediately following the hyperlink
 	// for the tool (based on the name attribute of the <tool> tag set described
 	// above).
 	//
 	// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-description
-	Description    string          `xml:"description"`
-	EdamTopics     *EdamTopics     `xml:"edam_topics,omitempty"`
-	EdamOperations *EdamOperations `xml:"edam_operations,omitempty"`
-	Xrefs          *Xrefs          `xml:"xrefs,omitempty"`
-	Creator        *Creator        `xml:"creator,omitempty"`
-	Requirements   *Requirements   `xml:"requirements"`
-	Command        *Command        `xml:"command"`
-	Inputs         *Inputs         `xml:"inputs"`
-	Outputs        *Outputs        `xml:"outputs"`
-	Id             string          `xml:"id,attr"`
-	Name           string          `xml:"name,attr"`
+	Description    string          `xml:"description" json:"description" yaml:"description"`
+	EdamTopics     *EdamTopics     `xml:"edam_topics,omitempty" json:"edam_topics,omitempty" yaml:"edam_topics,omitempty"`
+	EdamOperations *EdamOperations `xml:"edam_operations,omitempty" json:"edam_operations,omitempty" yaml:"edam_operations,omitempty"`
+	Xrefs          *Xrefs          `xml:"xrefs,omitempty" json:"xrefs,omitempty" yaml:"xrefs,omitempty"`
+	Creator        *Creator        `xml:"creator,omitempty" json:"creator,omitempty" yaml:"creator,omitempty"`
+	Requirements   *Requirements   `xml:"requirements" json:"requirements" yaml:"requirements"`
+	Command        *Command        `xml:"command" json:"command" yaml:"command"`
+	Inputs         *Inputs         `xml:"inputs" json:"inputs" yaml:"inputs"`
+	Outputs        *Outputs        `xml:"outputs" json:"outputs" yaml:"outputs"`
+	Tests          *Tests          `xml:"tests,omitempty" json:"tests,omitempty" yaml:"tests,omitempty"`
+	Citations      *Citations      `xml:"citations,omitempty" json:"citations,omitempty" yaml:"citations,omitempty"`
+	Id             string          `xml:"id,attr" json:"id" yaml:"id"`
+	Name           string          `xml:"name,attr" json:"name" yaml:"name"`
+	// Category is the section of the Galaxy tool panel listing the tool. It
+	// is not part of the tool XML, but of the tool_conf.xml.
+	//
+	// https://docs.galaxyproject.org/en/latest/admin/tool_panel.html
+	Category string `xml:"-" json:"category,omitempty" yaml:"category,omitempty"`
+	// Resources are the computing resources needed by the tool, written in
+	// the tool XML as resource requirements.
+	Resources *Resources `xml:"-" json:"resources,omitempty" yaml:"resources,omitempty"`
 }
 
 // Container tag set for the <edam_topic> tags. A tool can have any number of
@@ -37,8 +48,8 @@ type Tool struct {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-edam-topics
 type EdamTopics struct {
-	XMLName   xml.Name    `xml:"edam_topics,omitempty"`
-	EdamTopic []EdamTopic `xml:"edam_topic,omitempty"`
+	XMLName   xml.Name    `xml:"edam_topics,omitempty" json:"-" yaml:"-"`
+	EdamTopic []EdamTopic `xml:"edam_topic,omitempty" json:"edam_topic,omitempty" yaml:"edam_topic,omitempty"`
 }
 
 type EdamTopic string
@@ -48,8 +59,8 @@ type EdamTopic string
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-edam-operations
 type EdamOperations struct {
-	XMLName       xml.Name        `xml:"edam_operations"`
-	EdamOperation []EdamOperation `xml:"edam_operation"`
+	XMLName       xml.Name        `xml:"edam_operations" json:"-" yaml:"-"`
+	EdamOperation []EdamOperation `xml:"edam_operation" json:"edam_operation" yaml:"edam_operation"`
 }
 
 type EdamOperation string
@@ -59,44 +70,44 @@ type EdamOperation string
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-xrefs
 type Xrefs struct {
-	XMLName xml.Name `xml:"xrefs"`
-	Xref    []Xref   `xml:"xref"`
+	XMLName xml.Name `xml:"xrefs" json:"-" yaml:"-"`
+	Xref    []Xref   `xml:"xref" json:"xref" yaml:"xref"`
 }
 
 // The xref element specifies reference information according to a catalog.
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-xrefs-xref
 type Xref struct {
-	XMLName xml.Name `xml:"xref"`
+	XMLName xml.Name `xml:"xref" json:"-" yaml:"-"`
 	// Type of reference - currently bio.tools, bioconductor, and biii
 	// are the only supported options.
-	Type  string `xml:"type,attr"`
-	Value string `xml:",chardata"`
+	Type  string `xml:"type,attr" json:"type" yaml:"type"`
+	Value string `xml:",chardata" json:"value" yaml:"value"`
 }
 
 // The creator(s) of this work. See schema.org/creator.
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-creator
 type Creator struct {
-	XMLName      xml.Name      `xml:"creator,omitempty"`
-	Person       []Person      `xml:"person,omitempty"`
-	Organization *Organization `xml:"organization,omitempty"`
+	XMLName      xml.Name      `xml:"creator,omitempty" json:"-" yaml:"-"`
+	Person       []Person      `xml:"person,omitempty" json:"person,omitempty" yaml:"person,omitempty"`
+	Organization *Organization `xml:"organization,omitempty" json:"organization,omitempty" yaml:"organization,omitempty"`
 }
 
 // Describes a person. Tries to stay close to schema.org/Person.
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-creator-person
 type Person struct {
-	XMLName xml.Name `xml:"person,omitempty"`
-	Name    string   `xml:"name,omitempty"`
+	XMLName xml.Name `xml:"person,omitempty" json:"-" yaml:"-"`
+	Name    string   `xml:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
 }
 
 // Describes an organization. Tries to stay close to schema.org/Organization.
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-creator-organization
 type Organization struct {
-	XMLName xml.Name `xml:"organization,omitempty"`
-	Name    string   `xml:"name,omitempty"`
+	XMLName xml.Name `xml:"organization,omitempty" json:"-" yaml:"-"`
+	Name    string   `xml:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
 }
 
 // This is a container tag set for the requirement, resource and container tags
@@ -107,9 +118,11 @@ type Organization struct {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-requirements
 type Requirements struct {
-	XMLName     xml.Name      `xml:"requirements"`
-	Requirement []Requirement `xml:"requirement,omitempty"`
-	Container   []Container   `xml:"container,omitempty"`
+	XMLName     xml.Name      `xml:"requirements" json:"-" yaml:"-"`
+	Requirement []Requirement `xml:"requirement,omitempty" json:"requirement,omitempty" yaml:"requirement,omitempty"`
+	// Resource is derived from Tool.Resources when writing the tool XML.
+	Resource  []Resource  `xml:"resource,omitempty" json:"-" yaml:"-"`
+	Container []Container `xml:"container,omitempty" json:"container,omitempty" yaml:"container,omitempty"`
 }
 
 // This tag set is contained within the <requirements> tag set. Third party
@@ -122,9 +135,40 @@ type Requirements struct {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-requirements-requirement
 type Requirement struct {
-	XMLName xml.Name `xml:"requirement"`
-	Type    string   `xml:"type,attr"`
-	Version string   `xml:"version,attr"`
+	XMLName xml.Name `xml:"requirement" json:"-" yaml:"-"`
+	Type    string   `xml:"type,attr" json:"type" yaml:"type"`
+	Version string   `xml:"version,attr" json:"version" yaml:"version"`
+}
+
+// This tag set is contained within the <requirements> tag set. It describes
+// the computing resources needed by the tool, such as its cores or memory.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-requirements-resource
+type Resource struct {
+	XMLName xml.Name `xml:"resource" json:"-" yaml:"-"`
+	// Type of resource, such as cores_min or ram_min.
+	Type  string `xml:"type,attr" json:"type" yaml:"type"`
+	Value string `xml:",chardata" json:"value" yaml:"value"`
+}
+
+// Resources are the computing resources needed by a tool. They are a Baryon
+// extension, used by the job schedulers and written in the tool XML as
+// Resource requirements.
+type Resources struct {
+	// Cpus is the number of cores.
+	Cpus int `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	// Memory is the memory, in megabytes.
+	Memory int `json:"memory,omitempty" yaml:"memory,omitempty"`
+	// Time is the time limit, in minutes.
+	Time int `json:"time,omitempty" yaml:"time,omitempty"`
+}
+
+// Implements Validable.
+func (r Resources) Validate() error {
+	if r.Cpus < 0 || r.Memory < 0 || r.Time < 0 {
+		return fmt.Errorf("Resources cannot be negative.")
+	}
+	return nil
 }
 
 // This tag set is contained within the ‘requirements’ tag set. Galaxy can be
@@ -133,10 +177,10 @@ type Requirement struct {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-requirements-container
 type Container struct {
-	XMLName xml.Name `xml:"container"`
-	Type    string   `xml:"type,attr"`
-	Value   string   `xml:",chardata"`
-	Volumes []VolumeMapping
+	XMLName xml.Name        `xml:"container" json:"-" yaml:"-"`
+	Type    string          `xml:"type,attr" json:"type" yaml:"type"`
+	Value   string          `xml:",chardata" json:"value" yaml:"value"`
+	Volumes []VolumeMapping `json:"volumes,omitempty" yaml:"volumes,omitempty"`
 }
 
 // Implements Validable.
@@ -157,16 +201,16 @@ func (c Container) Validate() error {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-command
 type Command struct {
-	XMLName xml.Name `xml:"command"`
-	Value   string   `xml:",cdata"`
+	XMLName xml.Name `xml:"command" json:"-" yaml:"-"`
+	Value   string   `xml:",cdata" json:"value" yaml:"value"`
 }
 
 // Consists of all elements that define the tool’s input parameters.
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-inputs
 type Inputs struct {
-	XMLName xml.Name `xml:"inputs"`
-	Param   []Param  `xml:"param"`
+	XMLName xml.Name `xml:"inputs" json:"-" yaml:"-"`
+	Param   []Param  `xml:"param" json:"param" yaml:"param"`
 }
 
 // Contained within the <inputs> tag set - each of these specifies a field that
@@ -176,46 +220,37 @@ type Inputs struct {
 //
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-inputs-param
 type Param struct {
-	XMLName         xml.Name `xml:"param"`
-	Type            string   `xml:"type,attr"`
-	Name            string   `xml:"name,omitempty,attr"`
-	Value           string   `xml:"value,omitempty,attr"`
-	Options         []Option `xml:"option"`
-	Argument        string   `xml:"argument,omitempty"`
-	Label           string   `xml:"label,omitempty"`
-	Help            string   `xml:"help,omitempty"`
-	Optional        bool     `xml:"optional,omitempty"`
-	RefreshOnChange bool     `xml:"refresh_on_change,omitempty"`
+	XMLName         xml.Name `xml:"param" json:"-" yaml:"-"`
+	Type            string   `xml:"type,attr" json:"type" yaml:"type"`
+	Name            string   `xml:"name,omitempty,attr" json:"name,omitempty" yaml:"name,omitempty"`
+	Value           string   `xml:"value,omitempty,attr" json:"value,omitempty" yaml:"value,omitempty"`
+	Options         []Option `xml:"option" json:"options,omitempty" yaml:"options,omitempty"`
+	Argument        string   `xml:"argument,omitempty,attr" json:"argument,omitempty" yaml:"argument,omitempty"`
+	Label           string   `xml:"label,omitempty,attr" json:"label,omitempty" yaml:"label,omitempty"`
+	Help            string   `xml:"help,omitempty,attr" json:"help,omitempty" yaml:"help,omitempty"`
+	Optional        bool     `xml:"optional,omitempty,attr" json:"optional,omitempty" yaml:"optional,omitempty"`
+	RefreshOnChange bool     `xml:"refresh_on_change,omitempty,attr" json:"refresh_on_change,omitempty" yaml:"refresh_on_change,omitempty"`
+	TrueValue       string   `xml:"truevalue,omitempty,attr" json:"truevalue,omitempty" yaml:"truevalue,omitempty"`
+	FalseValue      string   `xml:"falsevalue,omitempty,attr" json:"falsevalue,omitempty" yaml:"falsevalue,omitempty"`
 }
 
 // https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-inputs-param-option
 type Option struct {
-	XMLName       xml.Name `xml:"option"`
-	Value         string   `xml:"value,attr"`
-	CanonicalName string   `xml:",innerxml"`
+	XMLName       xml.Name `xml:"option" json:"-" yaml:"-"`
+	Value         string   `xml:"value,attr" json:"value" yaml:"value"`
+	CanonicalName string   `xml:",innerxml" json:"canonical_name,omitempty" yaml:"canonical_name,omitempty"`
+}
+
+// ParamTypes are the allowed types of a Param.
+var ParamTypes = []string{
+	"text", "integer", "float", "boolean", "genomebuild", "select", "color",
+	"data_column", "hidden", "hidden_data", "baseurl", "file", "ftpfile",
+	"data", "data_collection", "drill_down",
 }
 
 // Implements Validable.
 func (p Param) Validate() error {
-	var allowedType map[string]struct{} = map[string]struct{}{
-		"text":            {},
-		"integer":         {},
-		"float":           {},
-		"boolean":         {},
-		"genomebuild":     {},
-		"select":          {},
-		"color":           {},
-		"data_column":     {},
-		"hidden":          {},
-		"hidden_data":     {},
-		"baseurl":         {},
-		"file":            {},
-		"ftpfile":         {},
-		"data":            {},
-		"data_collection": {},
-		"drill_down":      {},
-	}
-	if _, ok := allowedType[p.Type]; !ok {
+	if !slices.Contains(ParamTypes, p.Type) {
 		return fmt.Errorf("Type \"%s\" is not an allowed type.", p.Type)
 	}
 	if p.Optional && p.Value == "" {
@@ -233,8 +268,8 @@ func (p Param) Validate() error {
 //
 // https://docs.galaxyproject.org/en/master/dev/schema.html#tool-outputs
 type Outputs struct {
-	XMLName xml.Name `xml:"outputs"`
-	Data    []Data
+	XMLName xml.Name `xml:"outputs" json:"-" yaml:"-"`
+	Data    []Data   `xml:"data" json:"data" yaml:"data"`
 }
 
 // This tag set is contained within the <outputs> tag set, and it defines the
@@ -245,10 +280,10 @@ type Outputs struct {
 //
 // https://docs.galaxyproject.org/en/master/dev/schema.html#tool-outputs-data
 type Data struct {
-	XMLName xml.Name `xml:"data"`
-	Format  string   `xml:"format,omitempty,attr"`
-	Name    string   `xml:"name,omitempty,attr"`
-	Label   string   `xml:"label,omitempty,attr"`
+	XMLName xml.Name `xml:"data" json:"-" yaml:"-"`
+	Format  string   `xml:"format,omitempty,attr" json:"format,omitempty" yaml:"format,omitempty"`
+	Name    string   `xml:"name,omitempty,attr" json:"name,omitempty" yaml:"name,omitempty"`
+	Label   string   `xml:"label,omitempty,attr" json:"label,omitempty" yaml:"label,omitempty"`
 }
 
 // Implements Validable.
@@ -262,9 +297,95 @@ func (d Data) Validate() error {
 	return nil
 }
 
+// Container tag set for the <test> tags. Each test runs the tool with the
+// given params and compares its outputs with the expected files, read from
+// the test-data directory.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-tests
+type Tests struct {
+	XMLName xml.Name `xml:"tests" json:"-" yaml:"-"`
+	Test    []Test   `xml:"test" json:"test" yaml:"test"`
+}
+
+// This tag set defines a test of the tool.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-tests-test
+type Test struct {
+	XMLName xml.Name     `xml:"test" json:"-" yaml:"-"`
+	Param   []TestParam  `xml:"param" json:"param,omitempty" yaml:"param,omitempty"`
+	Output  []TestOutput `xml:"output" json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// The value of a param during a test. The value of a data param is the path
+// of a file, relative to the test-data directory.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-tests-test-param
+type TestParam struct {
+	XMLName xml.Name `xml:"param" json:"-" yaml:"-"`
+	Name    string   `xml:"name,attr" json:"name" yaml:"name"`
+	Value   string   `xml:"value,attr" json:"value" yaml:"value"`
+}
+
+// The file an output is compared with, relative to the test-data directory.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-tests-test-output
+type TestOutput struct {
+	XMLName xml.Name `xml:"output" json:"-" yaml:"-"`
+	Name    string   `xml:"name,attr" json:"name" yaml:"name"`
+	File    string   `xml:"file,attr" json:"file" yaml:"file"`
+}
+
+// Implements Validable.
+func (p TestParam) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("Name has no value specified.")
+	}
+	return nil
+}
+
+// Implements Validable.
+func (o TestOutput) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("Name has no value specified.")
+	}
+	if o.File == "" {
+		return fmt.Errorf("File has no value specified.")
+	}
+	return nil
+}
+
+// Tools may specify citations to be included in the work that uses them.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-citations
+type Citations struct {
+	XMLName  xml.Name   `xml:"citations" json:"-" yaml:"-"`
+	Citation []Citation `xml:"citation" json:"citation" yaml:"citation"`
+}
+
+// Each citation is either a DOI or a BibTeX entry.
+//
+// https://docs.galaxyproject.org/en/latest/dev/schema.html#tool-citations-citation
+type Citation struct {
+	XMLName xml.Name `xml:"citation" json:"-" yaml:"-"`
+	// Type of citation, doi or bibtex.
+	Type  string `xml:"type,attr" json:"type" yaml:"type"`
+	Value string `xml:",chardata" json:"value" yaml:"value"`
+}
+
+// Implements Validable.
+func (c Citation) Validate() error {
+	if c.Type != "doi" && c.Type != "bibtex" {
+		return fmt.Errorf("Type \"%s\" is not an allowed type.", c.Type)
+	}
+	if c.Value == "" {
+		return fmt.Errorf("Value has no value specified.")
+	}
+	return nil
+}
+
 // TODO: Integrate this with galaxy
 //   - research tool volume mapping.
 type VolumeMapping struct {
-	HostPath  string
-	GuestPath string
+	HostPath  string `json:"host_path" yaml:"host_path"`
+	GuestPath string `json:"guest_path" yaml:"guest_path"`
 }
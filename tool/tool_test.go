@@ -0,0 +1,207 @@
+package tool
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fullTool returns a Tool with every field set.
+func fullTool() *Tool {
+	return &Tool{
+		Id:             "t",
+		Name:           "T",
+		Description:    "A tool.",
+		Category:       "Tools",
+		EdamTopics:     &EdamTopics{EdamTopic: []EdamTopic{"topic_0080"}},
+		EdamOperations: &EdamOperations{EdamOperation: []EdamOperation{"operation_0004"}},
+		Xrefs:          &Xrefs{Xref: []Xref{{Type: "bio.tools", Value: "t"}}},
+		Creator: &Creator{
+			Person:       []Person{{Name: "Jane Doe"}},
+			Organization: &Organization{Name: "Lab"},
+		},
+		Requirements: &Requirements{
+			Requirement: []Requirement{{Type: "package", Version: "1.0"}},
+			Container: []Container{{
+				Type:    "docker",
+				Value:   "alpine:3",
+				Volumes: []VolumeMapping{{HostPath: "$dir", GuestPath: "/data"}},
+			}},
+		},
+		Command:   &Command{Value: "echo $dir"},
+		Resources: &Resources{Cpus: 2, Memory: 4096, Time: 60},
+		Inputs: &Inputs{Param: []Param{{
+			Type:            "select",
+			Name:            "dir",
+			Value:           "a",
+			Options:         []Option{{Value: "a", CanonicalName: "A"}},
+			Argument:        "--dir",
+			Label:           "Dir",
+			Help:            "a dir",
+			Optional:        true,
+			RefreshOnChange: true,
+			TrueValue:       "yes",
+			FalseValue:      "no",
+		}}},
+		Outputs: &Outputs{Data: []Data{{Format: "txt", Name: "out.txt", Label: "Out"}}},
+		Tests: &Tests{Test: []Test{{
+			Param:  []TestParam{{Name: "dir", Value: "a"}},
+			Output: []TestOutput{{Name: "out.txt", File: "expected.txt"}},
+		}}},
+		Citations: &Citations{Citation: []Citation{
+			{Type: "doi", Value: "10.1093/bioinformatics/btx000"},
+		}},
+	}
+}
+
+func Test_JSONRoundTrip(t *testing.T) {
+	in := fullTool()
+	out, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if strings.Contains(string(out), "XMLName") {
+		t.Errorf("Expected no XMLName in: %s", out)
+	}
+	back := &Tool{}
+	if err := json.Unmarshal(out, back); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !reflect.DeepEqual(in, back) {
+		t.Errorf("Expected %+v, got %+v", in, back)
+	}
+}
+
+func Test_YAMLRoundTrip(t *testing.T) {
+	in := fullTool()
+	out, err := yaml.Marshal(in)
+	if err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	back := &Tool{}
+	if err := yaml.Unmarshal(out, back); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	if !reflect.DeepEqual(in, back) {
+		t.Errorf("Expected %+v, got %+v", in, back)
+	}
+}
+
+// Test_JSONSchema checks that the schema describes every serialised field,
+// and nothing else.
+func Test_JSONSchema(t *testing.T) {
+	schema := map[string]any{}
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		t.Fatalf("Got this error: %v", err)
+	}
+	defs := schema["$defs"].(map[string]any)
+
+	// resolve returns the object schema of a property, following $ref and
+	// the first alternative of oneOf, and the items of arrays.
+	var resolve func(node map[string]any) map[string]any
+	resolve = func(node map[string]any) map[string]any {
+		if ref, ok := node["$ref"].(string); ok {
+			return resolve(defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any))
+		}
+		if oneOf, ok := node["oneOf"].([]any); ok {
+			return resolve(oneOf[0].(map[string]any))
+		}
+		if items, ok := node["items"].(map[string]any); ok {
+			return resolve(items)
+		}
+		return node
+	}
+
+	var check func(path string, typ reflect.Type, node map[string]any)
+	check = func(path string, typ reflect.Type, node map[string]any) {
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+		properties, _ := node["properties"].(map[string]any)
+		seen := map[string]bool{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if yamlName, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); yamlName != name {
+				t.Errorf("%s.%s: json name %q differs from yaml name %q",
+					path, field.Name, name, yamlName)
+			}
+			if name == "-" {
+				continue
+			}
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s is missing from the schema", path, name)
+				continue
+			}
+			seen[name] = true
+			check(path+"."+name, field.Type, resolve(property))
+		}
+		for name := range properties {
+			if !seen[name] {
+				t.Errorf("%s.%s is not a field of %s", path, name, typ.Name())
+			}
+		}
+	}
+	check("tool", reflect.TypeOf(Tool{}), schema)
+}
+
+func Test_Diff(t *testing.T) {
+	if changes := Diff(fullTool(), fullTool()); len(changes) != 0 {
+		t.Errorf("Expected no change, got %+v", changes)
+	}
+	new := fullTool()
+	new.Inputs.Param[0].Name = "directory"
+	new.Inputs.Param = append(new.Inputs.Param, Param{Type: "integer", Name: "n"})
+	new.Outputs.Data[0].Format = "tabular"
+	new.Requirements.Container[0].Value = "alpine:4"
+	changes := Diff(fullTool(), new)
+	want := []Change{
+		{Subject: "param dir", Message: "renamed to directory", Bump: Major},
+		{Subject: "param n", Message: "added, required without default value", Bump: Major},
+		{Subject: "output out.txt", Message: "format changed from txt to tabular", Bump: Major},
+		{Subject: "container", Message: "changed from alpine:3 (docker) to alpine:4 (docker)", Bump: Patch},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected %+v, got %+v", want, changes)
+	}
+
+	new = fullTool()
+	new.Inputs.Param[0].Options = append(new.Inputs.Param[0].Options, Option{Value: "b"})
+	new.Inputs.Param[0].Value = "b"
+	changes = Diff(fullTool(), new)
+	if BumpOf(changes) != Minor || len(changes) != 2 {
+		t.Errorf("Expected a default value change and an option added, got %+v", changes)
+	}
+}
+
+func Test_NextVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		bump    Bump
+		want    string
+	}{
+		{"1.2.3", Patch, "1.2.4"},
+		{"1.2.3+galaxy1", Minor, "1.3.0"},
+		{"1.2", Major, "2.0.0"},
+		{"0.1.0-rc1", Patch, "0.1.1"},
+	}
+	for _, test := range tests {
+		got, err := NextVersion(test.version, []Change{{Bump: test.bump}})
+		if err != nil || got != test.want {
+			t.Errorf("Expected %s for %s and %s, got %s (%v)", test.want, test.version, test.bump, got, err)
+		}
+	}
+	if got, _ := NextVersion("1.2.3", nil); got != "1.2.3" {
+		t.Errorf("Expected an unchanged version, got %s", got)
+	}
+	if _, err := NextVersion("v1.x", []Change{{Bump: Patch}}); err == nil {
+		t.Errorf("Expected error for v1.x")
+	}
+}
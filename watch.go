@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// defaultPollInterval is the interval between two polls of the watched
+// files.
+const defaultPollInterval = 500 * time.Millisecond
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher regenerates the tools of args when their files change, until its
+// context is done.
+//
+// The files are polled every interval, so that no platform specific API is
+// needed. A file is regenerated once it did not change during a whole
+// interval, so that the bursts of writes of an editor trigger a single
+// generation. A change of the template, of the values or of a configFile
+// regenerates all the files.
+type watcher struct {
+	options  *generateOptions
+	args     []string
+	interval time.Duration
+	stdout   io.Writer
+	stderr   io.Writer
+	// stamps are the stamps of the files when they were last generated.
+	stamps map[string]fileStamp
+}
+
+// watch implements generate --watch.
+func watch(ctx context.Context, options *generateOptions, args []string, stdout, stderr io.Writer) int {
+	w := &watcher{
+		options:  options,
+		args:     args,
+		interval: options.pollInterval,
+		stdout:   stdout,
+		stderr:   stderr,
+		stamps:   map[string]fileStamp{},
+	}
+	if w.interval <= 0 {
+		w.interval = defaultPollInterval
+	}
+	w.run(ctx)
+	return 0
+}
+
+// run polls the files until ctx is done.
+func (w *watcher) run(ctx context.Context) {
+	for path, stamp := range w.stat() {
+		w.stamps[path] = stamp
+	}
+	w.generate(nil, true)
+	fmt.Fprintln(w.stderr, "baryon: watching for changes, interrupt to stop")
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	// pending are the stamps of the changed files, generated once they
+	// are the same on the next poll.
+	pending := map[string]fileStamp{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := w.stat()
+		settled := true
+		for path, stamp := range current {
+			if w.stamps[path] == stamp {
+				delete(pending, path)
+				continue
+			}
+			if previous, ok := pending[path]; !ok || previous != stamp {
+				pending[path] = stamp
+				settled = false
+			}
+		}
+		if len(pending) == 0 || !settled {
+			continue
+		}
+		paths, shared := []string{}, false
+		for path, stamp := range pending {
+			w.stamps[path] = stamp
+			if path == w.options.template || path == w.options.values || w.options.site() ||
+				filepath.Base(path) == configFile {
+				shared = true
+			}
+			paths = append(paths, path)
+		}
+		clear(pending)
+		w.generate(paths, shared)
+	}
+}
+
+// stat returns the stamps of the inputs, expanded again to find the new
+// files, of their configFiles, and of the template and the values.
+func (w *watcher) stat() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	inputs, _, err := expandInputs(w.args)
+	if err != nil {
+		// Reported by the first generation, or temporary while a file
+		// is renamed.
+		inputs = []string{}
+		for _, arg := range w.args {
+			if _, err := os.Stat(arg); err == nil {
+				inputs = append(inputs, arg)
+			}
+		}
+	}
+	paths := append(inputs, w.options.template, w.options.values)
+	for _, input := range inputs {
+		paths = append(paths, configPaths(input)...)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+			stamps[path] = fileStamp{modTime: stat.ModTime(), size: stat.Size()}
+		}
+	}
+	return stamps
+}
+
+// generate regenerates the inputs among the changed paths, or all the
+// inputs when shared, printing the warnings of the regenerated files.
+func (w *watcher) generate(changed []string, shared bool) {
+	inputs, batch, err := expandInputs(w.args)
+	if err != nil {
+		fmt.Fprintf(w.stderr, "baryon: %v\n", err)
+		return
+	}
+	paths := inputs
+	if !shared {
+		paths = []string{}
+		for _, path := range inputs {
+			if slices.Contains(changed, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	if shared && w.options.targets != nil {
+		// The outputs follow the edits of the configFile.
+		c, err := findConfig(inputs[0])
+		if err != nil {
+			fmt.Fprintf(w.stderr, "baryon: %v\n", err)
+			return
+		}
+		if len(c.Outputs) > 0 {
+			w.options.targets = c
+		}
+	}
+	fmt.Fprintf(w.stderr, "[%s] generating %d file(s)\n", time.Now().Format(time.TimeOnly), len(paths))
+	switch {
+	case w.options.suite != "":
+		// A suite is generated as a whole.
+		if err := generateSuite(w.options, inputs, w.stderr); err != nil {
+			fmt.Fprintf(w.stderr, "baryon: %v\n", err)
+		}
+	case w.options.targets != nil:
+		generateTargets(w.options, paths, w.stdout, w.stderr)
+	case batch:
+		generateBatch(w.options, paths, w.stdout, w.stderr)
+	default:
+		if err := generate(w.options, paths[0], w.stdout, w.stderr); err != nil {
+			fmt.Fprintf(w.stderr, "%s: %v\n", paths[0], err)
+		}
+	}
+	for _, path := range paths {
+		source, err := readSource(path, w.options.parser)
+		if err != nil {
+			continue
+		}
+		for _, diagnostic := range source.lint() {
+			fmt.Fprint(w.stderr, source.formatDiagnostic(diagnostic))
+		}
+	}
+}